@@ -100,13 +100,32 @@ func (j *_jwt) GetDataToken(r *http.Request, key string) (interface{}, error) {
 	return nil, fmt.Errorf("key not found")
 }
 
+// extractor looks for the token in the Authorization header and in the
+// "authorization" argument.
+var extractor = &request.MultiExtractor{
+	request.OAuth2Extractor,
+	request.ArgumentExtractor{"authorization"},
+}
+
+// ParseUnverified extracts the token from an HTTP Request and returns its claims
+// without verifying the signature. The claims must not be trusted, use it only for
+// diagnostics such as audit logs.
+func ParseUnverified(r *http.Request) (map[string]interface{}, error) {
+	tokenString, err := extractor.ExtractToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	if _, _, err = jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
 // token extracts the token from an HTTP Request.
 func (j *_jwt) token(r *http.Request) (*jwt.Token, error) {
-	// token, err := request.ParseFromRequest(r, request.OAuth2Extractor, j.parseKeyFunc())
-	extractor := &request.MultiExtractor{
-		request.OAuth2Extractor,
-		request.ArgumentExtractor{"authorization"},
-	}
 	token, err := request.ParseFromRequest(r, extractor, j.parseKeyFunc())
 	if err != nil {
 		return nil, err
@@ -0,0 +1,192 @@
+package cerror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code is the machine-readable code of an Error.
+type Code string
+
+const (
+	CodeBadRequest              Code = "bad_request"
+	CodeUnauthorized            Code = "unauthorized"
+	CodeForbidden               Code = "forbidden"
+	CodeNotFound                Code = "not_found"
+	CodeMethodNotAllowed        Code = "method_not_allowed"
+	CodeConflict                Code = "conflict"
+	CodeRecordAlreadyRegistered Code = "record_already_registered"
+	CodePreconditionFailed      Code = "precondition_failed"
+	CodeValidation              Code = "validation_failed"
+	CodeTooManyRequests         Code = "too_many_requests"
+	CodeInternal                Code = "internal"
+	CodeNotImplemented          Code = "not_implemented"
+	CodeUnavailable             Code = "unavailable"
+	CodeTimeout                 Code = "timeout"
+)
+
+// Error is an error with a machine-readable code, the HTTP status of the response,
+// a message to the user, the internal cause and metadata. Errors are compared by
+// code with errors.Is, so an Error created from a sentinel, as with
+// ErrNotFound.Wrap(err), matches the sentinel.
+type Error struct {
+	Code    Code
+	Status  int
+	Message string
+	Cause   error
+	Meta    map[string]interface{}
+
+	stack []uintptr
+}
+
+// New creates an Error.
+func New(code Code, status int, message string) *Error {
+	e := &Error{Code: code, Status: status, Message: message}
+	if CaptureStack {
+		e.stack = callers()
+	}
+	return e
+}
+
+// Error implements interface Error, returning the message followed by the cause.
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the cause of the error.
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether target is an Error with the same code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of the error with the cause.
+func (e *Error) Wrap(cause error) *Error {
+	c := e.clone()
+	c.Cause = cause
+	return c
+}
+
+// WithMessage returns a copy of the error with the message.
+func (e *Error) WithMessage(format string, args ...interface{}) *Error {
+	c := e.clone()
+	c.Message = fmt.Sprintf(format, args...)
+	return c
+}
+
+// WithMeta returns a copy of the error with the metadata.
+func (e *Error) WithMeta(key string, value interface{}) *Error {
+	c := e.clone()
+	c.Meta[key] = value
+	return c
+}
+
+func (e *Error) clone() *Error {
+	c := *e
+	c.Meta = make(map[string]interface{}, len(e.Meta)+1)
+	for k, v := range e.Meta {
+		c.Meta[k] = v
+	}
+	if CaptureStack {
+		c.stack = callers()
+	}
+	return &c
+}
+
+// BadRequest returns an ErrBadRequest with the message.
+func BadRequest(message string) *Error {
+	return ErrBadRequest.WithMessage("%s", message)
+}
+
+// Forbidden returns an ErrForbidden with the message.
+func Forbidden(message string) *Error {
+	return ErrForbidden.WithMessage("%s", message)
+}
+
+// NotFound returns an ErrNotFound for the resource with the id, as in
+// cerror.NotFound("user", id).
+func NotFound(resource string, id interface{}) *Error {
+	return ErrNotFound.WithMessage("%s %v not found", resource, id).
+		WithMeta("resource", resource).
+		WithMeta("id", id)
+}
+
+// Conflict returns an ErrConflict for the resource with the id.
+func Conflict(resource string, id interface{}) *Error {
+	return ErrConflict.WithMessage("%s %v conflicts with the current state", resource, id).
+		WithMeta("resource", resource).
+		WithMeta("id", id)
+}
+
+// Internal returns an ErrInternalServer caused by err.
+func Internal(err error) *Error {
+	return ErrInternalServer.Wrap(err)
+}
+
+// FromStatus returns the sentinel error of the HTTP status, or an Error with the
+// status and the code of ErrBadRequest (4xx) or ErrInternalServer (5xx).
+func FromStatus(status int) *Error {
+	for _, e := range []*Error{
+		ErrBadRequest, ErrUnauthorized, ErrForbidden, ErrNotFound, ErrMethodNotAllowed, ErrConflict,
+		ErrPreconditionFailed, ErrTooManyRequests, ErrInternalServer, ErrNotImplemented, ErrUnavailable,
+		ErrTimeout,
+	} {
+		if e.Status == status {
+			return e
+		}
+	}
+
+	code := CodeInternal
+	if status < http.StatusInternalServerError {
+		code = CodeBadRequest
+	}
+	return New(code, status, http.StatusText(status))
+}
+
+// CodeOf returns the code of the error, CodeInternal for errors without code.
+func CodeOf(err error) Code {
+	var e *Error
+	var v *ErrValidateModel
+	switch {
+	case errors.As(err, &v):
+		return CodeValidation
+	case errors.As(err, &e):
+		return e.Code
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	default:
+		return CodeInternal
+	}
+}
+
+// StatusCode returns the HTTP status of the error: the status of an Error,
+// http.StatusBadRequest for an ErrValidateModel, http.StatusGatewayTimeout for a
+// context deadline and http.StatusInternalServerError for the others. A nil
+// error returns http.StatusOK.
+func StatusCode(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+
+	var e *Error
+	var v *ErrValidateModel
+	switch {
+	case errors.As(err, &v):
+		return ErrValidation.Status
+	case errors.As(err, &e):
+		return e.Status
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout.Status
+	default:
+		return http.StatusInternalServerError
+	}
+}
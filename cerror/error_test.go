@@ -0,0 +1,74 @@
+package cerror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestError_Is(t *testing.T) {
+	cause := errors.New("sql: no rows in result set")
+	err := fmt.Errorf("find user: %w", NotFound("user", 42).Wrap(cause))
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Error("errors.Is(err, ErrNotFound) = false")
+	}
+	if errors.Is(err, ErrConflict) {
+		t.Error("errors.Is(err, ErrConflict) = true")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("errors.Is(err, cause) = false")
+	}
+
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatal("errors.As(err, *Error) = false")
+	}
+	if e.Message != "user 42 not found" || e.Meta["resource"] != "user" || e.Meta["id"] != 42 {
+		t.Errorf("Error = %+v", e)
+	}
+	if e.Error() != "user 42 not found: sql: no rows in result set" {
+		t.Errorf("Error() = %q", e.Error())
+	}
+	if ErrNotFound.Message != http.StatusText(http.StatusNotFound) || ErrNotFound.Cause != nil ||
+		len(ErrNotFound.Meta) != 0 {
+		t.Errorf("ErrNotFound was modified: %+v", ErrNotFound)
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   Code
+	}{
+		{nil, http.StatusOK, CodeInternal},
+		{ErrBadRequest, http.StatusBadRequest, CodeBadRequest},
+		{NotFound("user", 1), http.StatusNotFound, CodeNotFound},
+		{fmt.Errorf("wrapped: %w", ErrTooManyRequests), http.StatusTooManyRequests, CodeTooManyRequests},
+		{ErrRecordAlreadyRegistered, http.StatusConflict, CodeRecordAlreadyRegistered},
+		{&ErrValidateModel{Msg: "invalid name"}, http.StatusBadRequest, CodeValidation},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout},
+		{errors.New("unknown"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, tt := range tests {
+		if status := StatusCode(tt.err); status != tt.status {
+			t.Errorf("StatusCode(%v) = %d, want %d", tt.err, status, tt.status)
+		}
+		if tt.err != nil {
+			if code := CodeOf(tt.err); code != tt.code {
+				t.Errorf("CodeOf(%v) = %s, want %s", tt.err, code, tt.code)
+			}
+		}
+	}
+
+	if !errors.Is(&ErrValidateModel{Msg: "invalid name"}, ErrValidation) {
+		t.Error("errors.Is(ErrValidateModel, ErrValidation) = false")
+	}
+	if ErrRecordAlreadyRegistered.Error() != "Record Already Registered" {
+		t.Errorf("ErrRecordAlreadyRegistered.Error() = %q", ErrRecordAlreadyRegistered.Error())
+	}
+}
@@ -2,31 +2,100 @@
 Package cerror provides customized errors that represent status for
 HTTP responses and specific treatments.
 
+Error carries a machine-readable code, the HTTP status, a message to the user,
+the internal cause and metadata. The sentinel errors are compared with errors.Is,
+even when wrapped or created by the helpers:
+
+	err := cerror.NotFound("user", id)
+	// ...
+
+	if errors.Is(err, cerror.ErrNotFound) {
+		// ...
+	}
+	status := cerror.StatusCode(err) // http.StatusNotFound
+
+IsRetryable reports whether an operation that failed may succeed if retried, as on
+network timeouts, broker leader elections and 503 responses, and RetryAfter returns
+the time to wait, when known:
+
+	if cerror.IsRetryable(err) {
+		after, _ := cerror.RetryAfter(err)
+		// ...
+	}
+
+When CaptureStack is enabled, the errors carry the stack trace of where they were
+created, printed with the %+v verb. A Reporter sends the Report of an error, with
+the stack trace, the request and a fingerprint that groups the same errors, and
+Deduplicate limits the reports of an error storm:
+
+	cerror.CaptureStack = true
+	reporter := cerror.Deduplicate(event.NewReporter("localhost"), time.Minute)
+
+	if err := reporter.Report(ctx, cerror.NewReport(err, r)); err != nil {
+	// ...
+
  */
 package cerror
 
 import (
-	"errors"
 	"net/http"
+	"strings"
 )
 
+// The sentinel errors, each with its code and HTTP status.
 var (
-	ErrBadRequest = errors.New(http.StatusText(http.StatusBadRequest))
-	ErrNotFound = errors.New(http.StatusText(http.StatusNotFound))
-	ErrInternalServer = errors.New(http.StatusText(http.StatusInternalServerError))
-	ErrUnauthorized = errors.New(http.StatusText(http.StatusUnauthorized))
+	ErrBadRequest         = statusError(CodeBadRequest, http.StatusBadRequest)
+	ErrUnauthorized       = statusError(CodeUnauthorized, http.StatusUnauthorized)
+	ErrForbidden          = statusError(CodeForbidden, http.StatusForbidden)
+	ErrNotFound           = statusError(CodeNotFound, http.StatusNotFound)
+	ErrMethodNotAllowed   = statusError(CodeMethodNotAllowed, http.StatusMethodNotAllowed)
+	ErrConflict           = statusError(CodeConflict, http.StatusConflict)
+	ErrPreconditionFailed = statusError(CodePreconditionFailed, http.StatusPreconditionFailed)
+	ErrValidation         = New(CodeValidation, http.StatusBadRequest, "Validation Failed")
+	ErrTooManyRequests    = statusError(CodeTooManyRequests, http.StatusTooManyRequests)
+	ErrInternalServer     = statusError(CodeInternal, http.StatusInternalServerError)
+	ErrNotImplemented     = statusError(CodeNotImplemented, http.StatusNotImplemented)
+	ErrUnavailable        = statusError(CodeUnavailable, http.StatusServiceUnavailable)
+	ErrTimeout            = statusError(CodeTimeout, http.StatusGatewayTimeout)
 )
 
+// statusError creates an Error with the text of the status as message.
+func statusError(code Code, status int) *Error {
+	return New(code, status, http.StatusText(status))
+}
+
+// FieldError is the validation error of a field of a model.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
 // ErrValidateModel error issued when validating a model's fields.
 type ErrValidateModel struct {
-	Msg string
+	Msg    string
+	Fields []FieldError
 }
 
-// Error implements interface Error
+// Error implements interface Error, returning Msg or the messages of the fields.
 func (e *ErrValidateModel) Error() string {
-	return e.Msg
+	if e.Msg != "" || len(e.Fields) == 0 {
+		return e.Msg
+	}
+
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Field + ": " + f.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Is reports whether target is ErrValidation.
+func (e *ErrValidateModel) Is(target error) bool {
+	return ErrValidation.Is(target)
 }
 
 // ErrRecordAlreadyRegistered indicates that the record already exists in the
 // data structure in question.
-var ErrRecordAlreadyRegistered = errors.New("Record Already Registered")
+var ErrRecordAlreadyRegistered = New(CodeRecordAlreadyRegistered, http.StatusConflict, "Record Already Registered")
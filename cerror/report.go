@@ -0,0 +1,156 @@
+package cerror
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Report is the detail of an error sent by a Reporter.
+type Report struct {
+	Message     string            `json:"message"`
+	Code        Code              `json:"code"`
+	Status      int               `json:"status"`
+	Stack       string            `json:"stack,omitempty"`
+	Fingerprint string            `json:"fingerprint"`
+	Request     map[string]string `json:"request,omitempty"`
+	Count       int               `json:"count"` // occurrences since the previous report.
+	Time        time.Time         `json:"time"`
+}
+
+// NewReport creates the Report of the error, with the method, path and user agent
+// of the request, when not nil. Errors with the same code, type of the root cause,
+// keys of the metadata and stack trace have the same fingerprint, so they are
+// grouped together. The message is not part of the fingerprint, as it may have
+// identifiers, as in "user 42 not found".
+func NewReport(err error, r *http.Request) *Report {
+	report := &Report{
+		Message: fmt.Sprintf("%v", err),
+		Code:    CodeOf(err),
+		Status:  StatusCode(err),
+		Stack:   StackTrace(err),
+		Count:   1,
+		Time:    time.Now(),
+	}
+
+	root := err
+	for errors.Unwrap(root) != nil {
+		root = errors.Unwrap(root)
+	}
+	var keys []string
+	var e *Error
+	if errors.As(err, &e) {
+		for k := range e.Meta {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%T|%s|%s", report.Code, root, strings.Join(keys, ","),
+		stripLines(report.Stack))))
+	report.Fingerprint = fmt.Sprintf("%x", sum[:8])
+
+	if r != nil {
+		report.Request = map[string]string{
+			"method":     r.Method,
+			"path":       r.URL.Path,
+			"user_agent": r.UserAgent(),
+		}
+	}
+
+	return report
+}
+
+// stripLines removes the lines of the files from the stack trace, so that the
+// fingerprint does not change when unrelated code is changed.
+func stripLines(stack string) string {
+	lines := strings.Split(stack, "\n")
+	for i, line := range lines {
+		if j := strings.LastIndex(line, ":"); strings.HasPrefix(line, "\t") && j > 0 {
+			lines[i] = line[:j]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Reporter sends the reports of the errors, as to an error tracking service.
+type Reporter interface {
+	Report(ctx context.Context, report *Report) error
+}
+
+// ReporterFunc adapts a function to a Reporter.
+type ReporterFunc func(ctx context.Context, report *Report) error
+
+// Report implements interface Reporter.
+func (f ReporterFunc) Report(ctx context.Context, report *Report) error {
+	return f(ctx, report)
+}
+
+// dedupReporter reports each fingerprint at most once per window.
+type dedupReporter struct {
+	reporter  Reporter
+	window    time.Duration
+	seen      map[string]*dedupEntry
+	lastSweep time.Time
+	mu        sync.Mutex // guard seen and lastSweep
+}
+
+type dedupEntry struct {
+	reportedAt time.Time
+	seenAt     time.Time
+	suppressed int
+}
+
+// Deduplicate returns a Reporter that sends the reports of the same fingerprint at
+// most once per window, so an error storm does not flood the reporter. The report
+// sent after the window counts the occurrences suppressed.
+func Deduplicate(reporter Reporter, window time.Duration) Reporter {
+	return &dedupReporter{
+		reporter: reporter,
+		window:   window,
+		seen:     make(map[string]*dedupEntry),
+	}
+}
+
+// Report implements interface Reporter.
+func (d *dedupReporter) Report(ctx context.Context, report *Report) error {
+	d.mu.Lock()
+	now := time.Now()
+	d.sweep(now)
+
+	entry, ok := d.seen[report.Fingerprint]
+	if ok && now.Sub(entry.reportedAt) < d.window {
+		entry.seenAt = now
+		entry.suppressed++
+		d.mu.Unlock()
+		return nil
+	}
+
+	r := *report
+	if ok {
+		r.Count += entry.suppressed
+	}
+	d.seen[report.Fingerprint] = &dedupEntry{reportedAt: now, seenAt: now}
+	d.mu.Unlock()
+
+	return d.reporter.Report(ctx, &r)
+}
+
+// sweep removes, at most once per window, the fingerprints without occurrences for
+// two windows.
+func (d *dedupReporter) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.window {
+		return
+	}
+	d.lastSweep = now
+	for fingerprint, entry := range d.seen {
+		if now.Sub(entry.seenAt) > 2*d.window {
+			delete(d.seen, fingerprint)
+		}
+	}
+}
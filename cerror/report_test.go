@@ -0,0 +1,72 @@
+package cerror
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewReport(t *testing.T) {
+	r := httptest.NewRequest("GET", "/users/42", nil)
+	r.Header.Set("User-Agent", "test")
+
+	report := NewReport(Internal(errors.New("connection lost")), r)
+	if report.Code != CodeInternal || report.Status != 500 || report.Count != 1 {
+		t.Errorf("Report = %+v", report)
+	}
+	if report.Request["method"] != "GET" || report.Request["path"] != "/users/42" ||
+		report.Request["user_agent"] != "test" {
+		t.Errorf("Request = %v", report.Request)
+	}
+
+	same := NewReport(Internal(errors.New("connection reset")), nil)
+	other := NewReport(Internal(ErrUnavailable), nil)
+	if report.Fingerprint == "" || report.Fingerprint != same.Fingerprint {
+		t.Errorf("Fingerprint = %q and %q, want equal", report.Fingerprint, same.Fingerprint)
+	}
+	if report.Fingerprint == other.Fingerprint {
+		t.Error("different errors have the same fingerprint")
+	}
+
+	user1 := NewReport(NotFound("user", 1), nil)
+	user2 := NewReport(NotFound("user", 2), nil)
+	if user1.Fingerprint != user2.Fingerprint {
+		t.Errorf("Fingerprint = %q and %q, want equal for different ids", user1.Fingerprint, user2.Fingerprint)
+	}
+	if user1.Fingerprint == NewReport(ErrNotFound, nil).Fingerprint {
+		t.Error("errors with different metadata have the same fingerprint")
+	}
+}
+
+func TestDeduplicate(t *testing.T) {
+	var reports []*Report
+	reporter := Deduplicate(ReporterFunc(func(_ context.Context, report *Report) error {
+		reports = append(reports, report)
+		return nil
+	}), 50*time.Millisecond)
+
+	err := Internal(errors.New("connection lost"))
+	for i := 0; i < 5; i++ {
+		reporter.Report(context.Background(), NewReport(err, nil))
+	}
+	reporter.Report(context.Background(), NewReport(ErrUnavailable, nil))
+
+	if len(reports) != 2 {
+		t.Fatalf("reports = %d, want 2", len(reports))
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	reporter.Report(context.Background(), NewReport(err, nil))
+
+	if len(reports) != 3 || reports[2].Count != 5 {
+		t.Errorf("reports = %d, Count = %d, want 3 and 5", len(reports), reports[len(reports)-1].Count)
+	}
+	time.Sleep(110 * time.Millisecond)
+	reporter.Report(context.Background(), NewReport(ErrNotFound, nil))
+
+	if seen := len(reporter.(*dedupReporter).seen); seen != 1 {
+		t.Errorf("fingerprints = %d after two windows, want 1", seen)
+	}
+}
@@ -0,0 +1,120 @@
+package cerror
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// retryableError marks an error as retryable or permanent.
+type retryableError struct {
+	err        error
+	retryable  bool
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string             { return e.err.Error() }
+func (e *retryableError) Unwrap() error             { return e.err }
+func (e *retryableError) Retryable() bool           { return e.retryable }
+func (e *retryableError) RetryAfter() time.Duration { return e.retryAfter }
+
+// Retry marks the error as retryable after the duration, zero when unknown.
+func Retry(err error, after time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err, retryable: true, retryAfter: after}
+}
+
+// retryAfterError carries the time to wait before retrying, without classifying
+// the error.
+type retryAfterError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryAfterError) Error() string             { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error             { return e.err }
+func (e *retryAfterError) RetryAfter() time.Duration { return e.retryAfter }
+
+// WithRetryAfter adds the time to wait before retrying to the error, as sent in a
+// Retry-After header, without marking it as retryable: IsRetryable still decides
+// by its cause.
+func WithRetryAfter(err error, after time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryAfterError{err: err, retryAfter: after}
+}
+
+// Permanent marks the error as not retryable, even if its cause is.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err}
+}
+
+// IsRetryable reports whether the operation that failed with the error may
+// succeed if retried. The error is retryable if:
+//
+//   - it, or an error in its chain, was marked with Retry; the outermost mark of
+//     Retry or Permanent prevails;
+//   - an error in its chain has a method Retryable() bool or Temporary() bool that
+//     returns true, as the errors of the Kafka brokers during a leader election;
+//   - it is a timeout, a refused or reset connection or an unexpected EOF;
+//   - it is an Error with status 408, 429, 502, 503 or 504.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var r interface{ Retryable() bool }
+	if errors.As(err, &r) {
+		return r.Retryable()
+	}
+
+	var t interface{ Temporary() bool }
+	if errors.As(err, &t) && t.Temporary() {
+		return true
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+
+	var e *Error
+	if errors.As(err, &e) && retryableStatus(e.Status) {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// RetryAfter returns the time to wait before retrying, when known, as the time
+// sent in the Retry-After header of a response.
+func RetryAfter(err error) (time.Duration, bool) {
+	var r interface{ RetryAfter() time.Duration }
+	if errors.As(err, &r) && r.RetryAfter() > 0 {
+		return r.RetryAfter(), true
+	}
+	return 0, false
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
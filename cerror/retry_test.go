@@ -0,0 +1,71 @@
+package cerror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type temporaryError struct{ temporary bool }
+
+func (e temporaryError) Error() string   { return "temporary" }
+func (e temporaryError) Temporary() bool { return e.temporary }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"nil", nil, false},
+		{"unknown", errors.New("unknown"), false},
+		{"not found", ErrNotFound, false},
+		{"unavailable", ErrUnavailable, true},
+		{"too many requests", fmt.Errorf("call: %w", ErrTooManyRequests), true},
+		{"bad gateway", FromStatus(502), true},
+		{"deadline", context.DeadlineExceeded, true},
+		{"canceled", context.Canceled, false},
+		{"net timeout", &net.OpError{Op: "dial", Err: &timeoutError{}}, true},
+		{"connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{"temporary", temporaryError{true}, true},
+		{"not temporary", temporaryError{false}, false},
+		{"retry", Retry(errors.New("unknown"), time.Second), true},
+		{"retry after not found", WithRetryAfter(ErrNotFound, time.Second), false},
+		{"retry after unavailable", WithRetryAfter(ErrUnavailable, time.Second), true},
+		{"permanent", Permanent(ErrUnavailable), false},
+		{"internal caused by timeout", Internal(context.DeadlineExceeded), true},
+	}
+
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.retryable {
+			t.Errorf("%s: IsRetryable() = %v, want %v", tt.name, got, tt.retryable)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	err := fmt.Errorf("call: %w", Retry(ErrUnavailable, 3*time.Second))
+
+	if after, ok := RetryAfter(err); !ok || after != 3*time.Second {
+		t.Errorf("RetryAfter() = %v, %v, want 3s", after, ok)
+	}
+	if !errors.Is(err, ErrUnavailable) {
+		t.Error("errors.Is(err, ErrUnavailable) = false")
+	}
+	if after, ok := RetryAfter(WithRetryAfter(ErrNotFound, time.Second)); !ok || after != time.Second {
+		t.Errorf("RetryAfter(WithRetryAfter()) = %v, %v, want 1s", after, ok)
+	}
+	if _, ok := RetryAfter(ErrUnavailable); ok {
+		t.Error("RetryAfter(ErrUnavailable) ok = true")
+	}
+}
+
+type timeoutError struct{}
+
+func (e *timeoutError) Error() string   { return "i/o timeout" }
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
@@ -0,0 +1,96 @@
+package cerror
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+)
+
+// CaptureStack enables the capture of the stack trace when an Error is created,
+// wrapped or copied, as by New, Wrap, WithMessage and the helpers. The stack trace
+// is printed with the %+v verb. Capturing has a cost, so it is disabled by default.
+var CaptureStack bool
+
+const pkgPrefix = "github.com/tsmweb/go-helper-api/cerror."
+
+// WithStack returns a copy of the error with the stack trace of the caller, even
+// if CaptureStack is disabled.
+func (e *Error) WithStack() *Error {
+	c := e.clone()
+	c.stack = callers()
+	return c
+}
+
+// StackTrace returns the frames of the stack trace captured when the error was
+// created, or nil.
+func (e *Error) StackTrace() []runtime.Frame {
+	if len(e.stack) == 0 {
+		return nil
+	}
+
+	var stack []runtime.Frame
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		frame, more := frames.Next()
+		// skips the frames of this package, which are not where the error originated.
+		if len(stack) > 0 || !strings.HasPrefix(frame.Function, pkgPrefix) ||
+			strings.HasSuffix(frame.File, "_test.go") {
+			stack = append(stack, frame)
+		}
+		if !more {
+			return stack
+		}
+	}
+}
+
+// Format implements interface fmt.Formatter. The %+v verb prints the message,
+// the causes and the stack trace of each error in the chain.
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		io.WriteString(s, e.Message)
+		writeStack(s, e.StackTrace())
+		if e.Cause != nil {
+			fmt.Fprintf(s, "\ncaused by: %+v", e.Cause)
+		}
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		io.WriteString(s, e.Error())
+	}
+}
+
+// StackTrace returns the stack trace of the innermost Error in the chain of err
+// with a stack trace, the closest to where the error originated, formatted as
+// runtime/debug.Stack.
+func StackTrace(err error) string {
+	var stack []runtime.Frame
+	for err != nil {
+		var e *Error
+		if !errors.As(err, &e) {
+			break
+		}
+		if s := e.StackTrace(); s != nil {
+			stack = s
+		}
+		err = e.Cause
+	}
+
+	var sb strings.Builder
+	writeStack(&sb, stack)
+	return strings.TrimPrefix(sb.String(), "\n")
+}
+
+func writeStack(w io.Writer, stack []runtime.Frame) {
+	for _, frame := range stack {
+		fmt.Fprintf(w, "\n%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+	}
+}
+
+func callers() []uintptr {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs) // skips runtime.Callers, callers and its caller.
+	return pcs[:n]
+}
@@ -0,0 +1,54 @@
+package cerror
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func findUser() error {
+	return NotFound("user", 42)
+}
+
+func TestError_StackTrace(t *testing.T) {
+	if stack := findUser().(*Error).StackTrace(); stack != nil {
+		t.Errorf("StackTrace() = %v, want nil with CaptureStack disabled", stack)
+	}
+
+	CaptureStack = true
+	defer func() { CaptureStack = false }()
+
+	err := findUser().(*Error)
+	stack := err.StackTrace()
+	if len(stack) == 0 || !strings.HasSuffix(stack[0].Function, "cerror.findUser") {
+		t.Fatalf("StackTrace() = %v, want findUser first", stack)
+	}
+
+	s := fmt.Sprintf("%+v", Internal(err))
+	if !strings.Contains(s, "caused by: user 42 not found") || !strings.Contains(s, "stack_test.go") {
+		t.Errorf("%%+v = %q", s)
+	}
+	if s := fmt.Sprintf("%v", err); s != "user 42 not found" {
+		t.Errorf("%%v = %q", s)
+	}
+
+	trace := StackTrace(fmt.Errorf("wrapped: %w", Internal(err)))
+	if !strings.HasPrefix(trace, pkgPrefix+"findUser\n\t") {
+		t.Errorf("StackTrace() = %q, want the innermost stack", trace)
+	}
+}
+
+func TestError_WithStack(t *testing.T) {
+	err := ErrUnavailable.WithStack()
+
+	if len(err.StackTrace()) == 0 {
+		t.Error("StackTrace() is empty")
+	}
+	if len(ErrUnavailable.StackTrace()) != 0 {
+		t.Error("ErrUnavailable was modified")
+	}
+	if !errors.Is(err, ErrUnavailable) {
+		t.Error("errors.Is(err, ErrUnavailable) = false")
+	}
+}
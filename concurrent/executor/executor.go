@@ -95,6 +95,17 @@ func (e *Executor) IsTerminated() bool {
 	}
 }
 
+// Running returns the number of running tasks.
+func (e *Executor) Running() int {
+	return len(e.sema)
+}
+
+// Load returns the fraction of the executor in use, between 0 and 1. When the
+// load is 1, Schedule blocks until a task is completed.
+func (e *Executor) Load() float64 {
+	return float64(e.Running()) / float64(cap(e.sema))
+}
+
 func (e *Executor) worker(task func(ctx context.Context)) {
 	done := make(chan struct{})
 	e.wg.Add(1)
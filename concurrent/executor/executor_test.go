@@ -70,3 +70,20 @@ func (p *PrintTask) Run() func(ctx context.Context) {
 		}
 	}
 }
+
+func TestExecutor_Load(t *testing.T) {
+	exe := New(4)
+	defer exe.Shutdown()
+
+	release := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		if err := exe.Schedule(func(ctx context.Context) { <-release }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if running, load := exe.Running(), exe.Load(); running != 2 || load != 0.5 {
+		t.Errorf("Running() = %d, Load() = %v, want 2 and 0.5", running, load)
+	}
+	close(release)
+}
@@ -0,0 +1,69 @@
+package executor
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/cerror"
+)
+
+// RetryOptions configures Retry.
+type RetryOptions struct {
+	// MaxAttempts is the maximum number of attempts. Default 3.
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the second attempt. Default 100 milliseconds.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum wait between attempts. Default 10 seconds.
+	MaxBackoff time.Duration
+}
+
+// Retry calls fn until it succeeds, fails with an error not retryable (see
+// cerror.IsRetryable), the attempts are exhausted or ctx is done, returning the
+// last error. The wait between attempts doubles from InitialBackoff, with jitter,
+// unless the error has a hint (see cerror.RetryAfter).
+//
+//	err := executor.Retry(ctx, executor.RetryOptions{MaxAttempts: 5}, func(ctx context.Context) error {
+//		return producer.Publish(ctx, key, value)
+//	})
+func Retry(ctx context.Context, opts RetryOptions, fn func(ctx context.Context) error) error {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 10 * time.Second
+	}
+
+	backoff := opts.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= opts.MaxAttempts || !cerror.IsRetryable(err) {
+			return err
+		}
+
+		wait, ok := cerror.RetryAfter(err)
+		if !ok {
+			wait = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)) // jitter
+			backoff *= 2
+			if backoff > opts.MaxBackoff {
+				backoff = opts.MaxBackoff
+			}
+		}
+		if wait > opts.MaxBackoff {
+			wait = opts.MaxBackoff
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
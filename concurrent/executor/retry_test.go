@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/cerror"
+)
+
+func TestRetry(t *testing.T) {
+	opts := RetryOptions{MaxAttempts: 4, InitialBackoff: time.Millisecond}
+
+	attempts := 0
+	err := Retry(context.Background(), opts, func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return cerror.ErrUnavailable
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("Retry() = %v after %d attempts, want nil after 3", err, attempts)
+	}
+
+	attempts = 0
+	err = Retry(context.Background(), opts, func(ctx context.Context) error {
+		attempts++
+		return cerror.ErrNotFound
+	})
+	if !errors.Is(err, cerror.ErrNotFound) || attempts != 1 {
+		t.Errorf("Retry() = %v after %d attempts, want ErrNotFound after 1", err, attempts)
+	}
+
+	attempts = 0
+	err = Retry(context.Background(), opts, func(ctx context.Context) error {
+		attempts++
+		return cerror.ErrTimeout
+	})
+	if !errors.Is(err, cerror.ErrTimeout) || attempts != 4 {
+		t.Errorf("Retry() = %v after %d attempts, want ErrTimeout after 4", err, attempts)
+	}
+}
+
+func TestRetry_RetryAfter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	start := time.Now()
+	err := Retry(ctx, RetryOptions{}, func(ctx context.Context) error {
+		attempts++
+		return cerror.Retry(cerror.ErrTooManyRequests, time.Second)
+	})
+
+	if !errors.Is(err, cerror.ErrTooManyRequests) || attempts != 1 {
+		t.Errorf("Retry() = %v after %d attempts", err, attempts)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Retry() did not stop with the context: %v", elapsed)
+	}
+}
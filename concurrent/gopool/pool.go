@@ -98,6 +98,26 @@ func (p *Pool) ScheduleTimeout(timeout time.Duration, task func(ctx context.Cont
 	return p.schedule(task, time.After(timeout))
 }
 
+// Running returns the number of running workers.
+func (p *Pool) Running() int {
+	return len(p.sema)
+}
+
+// Queued returns the number of tasks waiting in the work queue.
+func (p *Pool) Queued() int {
+	return len(p.work)
+}
+
+// Load returns the fraction of the workers and of the work queue in use, between
+// 0 and 1. When the load is 1, Schedule blocks until a worker is free.
+func (p *Pool) Load() float64 {
+	capacity := cap(p.sema) + cap(p.work)
+	if capacity == 0 {
+		return 1
+	}
+	return float64(p.Running()+p.Queued()) / float64(capacity)
+}
+
 func (p *Pool) schedule(task func(ctx context.Context), timeout <-chan time.Time) error {
 	p.mu.RLock()
 	if p.closed {
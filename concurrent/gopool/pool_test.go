@@ -71,3 +71,20 @@ func (p *PrintTask) Run() func(ctx context.Context) {
 		}
 	}
 }
+
+func TestPool_Load(t *testing.T) {
+	pool := New(2, 2)
+	defer pool.Close()
+
+	release := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		if err := pool.Schedule(func(ctx context.Context) { <-release }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if load := pool.Load(); load != 0.75 {
+		t.Errorf("Load() = %v, want 0.75", load)
+	}
+	close(release)
+}
@@ -3,19 +3,18 @@ module github.com/tsmweb/go-helper-api
 go 1.19
 
 require (
+	github.com/andybalholm/brotli v1.0.5
 	github.com/golang-jwt/jwt/v4 v4.4.2
 	github.com/google/uuid v1.3.0
-	github.com/gorilla/handlers v1.5.1
+	github.com/klauspost/compress v1.15.9
 	github.com/mackerelio/go-osstat v0.2.3
 	github.com/segmentio/kafka-go v0.4.34
 	github.com/stretchr/testify v1.8.0
-	github.com/xi2/httpgzip v0.0.0-20190509075255-932ab5e254ae
+	golang.org/x/crypto v0.0.0-20220622213112-05595931fe9d
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/felixge/httpsnoop v1.0.3 // indirect
-	github.com/klauspost/compress v1.15.9 // indirect
 	github.com/pierrec/lz4/v4 v4.1.15 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.4.0 // indirect
@@ -0,0 +1,62 @@
+package httputil
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/cerror"
+)
+
+// CheckResponse returns nil if the response of a downstream service has status 1xx,
+// 2xx or 3xx. Otherwise it returns the cerror.Error of the status, with the
+// message of the error sent as problem details or by RespondWithError, and the
+// time of the Retry-After header as hint (see cerror.RetryAfter). The hint does not
+// make the error retryable, which depends on the status (see cerror.IsRetryable). It reads up to 64 KB of the body of a failed response, which
+// the caller still has to close.
+func CheckResponse(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	err := cerror.FromStatus(resp.StatusCode)
+
+	var body struct {
+		Detail       string `json:"detail"`
+		ErrorMessage string `json:"error_message"`
+	}
+	if data, rerr := io.ReadAll(io.LimitReader(resp.Body, 64<<10)); rerr == nil &&
+		json.Unmarshal(data, &body) == nil {
+		switch {
+		case body.Detail != "":
+			err = err.WithMessage("%s", body.Detail)
+		case body.ErrorMessage != "":
+			err = err.WithMessage("%s", body.ErrorMessage)
+		}
+	}
+
+	if after, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		return cerror.WithRetryAfter(err, after)
+	}
+	return err
+}
+
+// parseRetryAfter parses the Retry-After header, in seconds or as a date.
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
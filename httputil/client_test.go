@@ -0,0 +1,63 @@
+package httputil
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/cerror"
+)
+
+func TestCheckResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusNoContent)
+		case "/unavailable":
+			w.Header().Set("Retry-After", "7")
+			RespondWithError(w, http.StatusServiceUnavailable, "maintenance")
+		case "/users/1":
+			RespondWithProblem(w, r, cerror.NotFound("user", 1))
+		case "/users/2":
+			w.Header().Set("Retry-After", "5")
+			RespondWithProblem(w, r, cerror.NotFound("user", 2))
+		}
+	}))
+	defer server.Close()
+
+	get := func(path string) error {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		return CheckResponse(resp)
+	}
+
+	if err := get("/ok"); err != nil {
+		t.Errorf("CheckResponse(204) = %v", err)
+	}
+
+	err := get("/unavailable")
+	if !errors.Is(err, cerror.ErrUnavailable) || !cerror.IsRetryable(err) || err.Error() != "maintenance" {
+		t.Errorf("CheckResponse(503) = %v, retryable %v", err, cerror.IsRetryable(err))
+	}
+	if after, ok := cerror.RetryAfter(err); !ok || after != 7*time.Second {
+		t.Errorf("RetryAfter() = %v, %v, want 7s", after, ok)
+	}
+
+	err = get("/users/1")
+	if !errors.Is(err, cerror.ErrNotFound) || cerror.IsRetryable(err) || err.Error() != "user 1 not found" {
+		t.Errorf("CheckResponse(404) = %v", err)
+	}
+
+	err = get("/users/2")
+	if !errors.Is(err, cerror.ErrNotFound) || cerror.IsRetryable(err) {
+		t.Errorf("CheckResponse(404 with Retry-After) = %v, retryable %v", err, cerror.IsRetryable(err))
+	}
+	if after, ok := cerror.RetryAfter(err); !ok || after != 5*time.Second {
+		t.Errorf("RetryAfter() = %v, %v, want 5s", after, ok)
+	}
+}
@@ -1,12 +1,29 @@
 /*
 Package httputil provides http utility methods.
+
+RespondWithProblem responds with the problem details (RFC 7807) of an error,
+taking the status, code and field errors from the cerror errors:
+
+	if debug {
+		httputil.ProblemHook = httputil.ShowInternalDetails // cause and 5xx details.
+	}
+
+	user, err := repository.Get(id)
+	if err != nil {
+		httputil.RespondWithProblem(w, r, err) // cerror.NotFound("user", id)
+		return
+	}
+
+With a Catalog, the problems are localized in the language of the Accept-Language
+header of the request, as "Não Encontrado" for "pt-BR":
+
+	httputil.Catalog = i18n.Default
 */
 
 package httputil
 
 import (
 	"encoding/json"
-	"fmt"
 	"mime"
 	"net/http"
 	"strings"
@@ -95,7 +112,10 @@ func RespondWithHeader(w http.ResponseWriter, status int, h Headers) {
 
 func RespondWithError(w http.ResponseWriter, status int, message string) {
 	RespondWithHeader(w, status, Headers{"Content-Type": MimeTypeText(MimeApplicationJSON)})
-	w.Write([]byte(fmt.Sprintf(`{"error_message": "%s"}`, message)))
+	data, _ := json.Marshal(struct {
+		ErrorMessage string `json:"error_message"`
+	}{message})
+	w.Write(data)
 }
 
 func RespondWithJSON(w http.ResponseWriter, status int, data interface{}) {
@@ -0,0 +1,227 @@
+package httputil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/tsmweb/go-helper-api/cerror"
+	"github.com/tsmweb/go-helper-api/i18n"
+	"github.com/tsmweb/go-helper-api/requestid"
+)
+
+// MimeProblemJSON is the media type of the problem details (RFC 7807).
+const MimeProblemJSON = "application/problem+json"
+
+// ProblemTypeBase is the prefix of the type of the problems, followed by the code
+// of the error, as "https://api.example.com/problems/" results in
+// "https://api.example.com/problems/not_found". When empty, the type is "about:blank".
+var ProblemTypeBase string
+
+// ProblemHook is called with each problem before it is sent, and may change it,
+// as to add extension members. See ShowInternalDetails.
+var ProblemHook func(r *http.Request, err error, p *Problem)
+
+// ErrorReporter, when not nil, reports the server errors (5xx) responded by
+// RespondWithProblem, with the context of the request:
+//
+//	httputil.ErrorReporter = cerror.Deduplicate(event.NewReporter("localhost"), time.Minute)
+var ErrorReporter cerror.Reporter
+
+// Catalog, when not nil, localizes the title, the detail and the field errors of
+// the problems in the locale of the Accept-Language header of the request (see
+// i18n.Catalog.Match). The title is the message of the code of the error; the
+// detail, the message of the code followed by ".detail" when the metadata of the
+// error has its parameters, or the title when the error has the default message;
+// and each field error, the message of "validation." followed by the rule, with
+// the field and param as parameters:
+//
+//	httputil.Catalog = i18n.Default
+var Catalog *i18n.Catalog
+
+// Problem represents the problem details of an error response (RFC 7807). The
+// extension members "code", "errors" (the field errors of a validation), "cause"
+// and the metadata of a cerror.Error are sent alongside the standard members.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Code       cerror.Code
+	Errors     []cerror.FieldError
+	Extensions map[string]interface{}
+
+	// Language is the locale of the messages, sent in the Content-Language header,
+	// when localized by Catalog.
+	Language string
+}
+
+// MarshalJSON implements interface json.Marshaler.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+7)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+
+	members["type"] = p.Type
+	members["title"] = p.Title
+	members["status"] = p.Status
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+	if p.Code != "" {
+		members["code"] = p.Code
+	}
+	if len(p.Errors) > 0 {
+		members["errors"] = p.Errors
+	}
+
+	return json.Marshal(members)
+}
+
+// NewProblem creates the Problem of the error. The status and code are taken from
+// cerror.StatusCode and cerror.CodeOf; the detail is the message of a cerror.Error
+// or the text of any other error. The cause of the errors and the detail and
+// metadata of the server errors (5xx), which may reveal the internals of the
+// service, as driver or network messages, are not sent unless by
+// ShowInternalDetails.
+func NewProblem(r *http.Request, err error) *Problem {
+	status := cerror.StatusCode(err)
+	code := cerror.CodeOf(err)
+
+	p := &Problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(status),
+		Status:     status,
+		Code:       code,
+		Extensions: make(map[string]interface{}),
+	}
+	if ProblemTypeBase != "" {
+		p.Type = ProblemTypeBase + string(code)
+	}
+	if r != nil {
+		p.Instance = r.URL.Path
+		if id, ok := requestid.FromContext(r.Context()); ok {
+			p.Extensions["request_id"] = id
+		}
+	}
+
+	describe(p, err, false)
+
+	if Catalog != nil {
+		localize(p, r, err)
+	}
+
+	return p
+}
+
+// describe sets the detail, the field errors and the metadata of the error. The
+// cause, and the detail and metadata of the server errors, are only set with
+// internal.
+func describe(p *Problem, err error, internal bool) {
+	public := internal || p.Status < http.StatusInternalServerError
+
+	var e *cerror.Error
+	var v *cerror.ErrValidateModel
+	switch {
+	case errors.As(err, &v):
+		p.Detail = v.Msg
+		p.Errors = v.Fields
+	case errors.As(err, &e):
+		if public {
+			p.Detail = e.Message
+			for k, value := range e.Meta {
+				p.Extensions[k] = value
+			}
+		}
+		if internal && e.Cause != nil {
+			p.Extensions["cause"] = e.Cause.Error()
+		}
+	case err != nil && public:
+		p.Detail = err.Error()
+	}
+}
+
+// localize translates the problem to the locale of the request. The messages not
+// found in the catalog are kept.
+func localize(p *Problem, r *http.Request, err error) {
+	var locale string
+	if r != nil {
+		locale = Catalog.Match(r.Header.Get("Accept-Language"))
+	} else {
+		locale = Catalog.Match("")
+	}
+	p.Language = locale
+
+	title, ok := Catalog.Message(locale, string(p.Code), nil)
+	if ok {
+		p.Title = title
+	}
+
+	var e *cerror.Error
+	if errors.As(err, &e) {
+		detail, ok := Catalog.Message(locale, string(e.Code)+".detail", e.Meta)
+		if ok && p.Status < http.StatusInternalServerError {
+			p.Detail = detail
+		} else if e.Message == http.StatusText(e.Status) && title != "" {
+			p.Detail = title
+		}
+	}
+
+	if len(p.Errors) > 0 {
+		errs := make([]cerror.FieldError, len(p.Errors))
+		for i, fe := range p.Errors {
+			errs[i] = fe
+			params := map[string]interface{}{"field": fe.Field, "param": fe.Param}
+			if msg, ok := Catalog.Message(locale, "validation."+fe.Rule, params); ok {
+				errs[i].Message = msg
+			}
+		}
+		p.Errors = errs
+	}
+}
+
+// ShowInternalDetails is a ProblemHook that adds the cause of the errors and the
+// detail and metadata of the server errors (5xx), hidden by default. Use it only
+// in development, as these may reveal the internals of the service:
+//
+//	if debug {
+//		httputil.ProblemHook = httputil.ShowInternalDetails
+//	}
+func ShowInternalDetails(_ *http.Request, err error, p *Problem) {
+	describe(p, err, true)
+}
+
+// RespondWithProblem responds with the problem details of the error, as
+// application/problem+json. Server errors are reported to ErrorReporter.
+func RespondWithProblem(w http.ResponseWriter, r *http.Request, err error) {
+	p := NewProblem(r, err)
+	if ErrorReporter != nil && p.Status >= http.StatusInternalServerError {
+		ctx := context.Background()
+		if r != nil {
+			ctx = r.Context()
+		}
+		ErrorReporter.Report(ctx, cerror.NewReport(err, r))
+	}
+	if ProblemHook != nil {
+		ProblemHook(r, err, p)
+	}
+
+	data, merr := json.Marshal(p)
+	if merr != nil {
+		RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	headers := Headers{"Content-Type": MimeProblemJSON}
+	if p.Language != "" {
+		headers["Content-Language"] = p.Language
+	}
+	RespondWithHeader(w, p.Status, headers)
+	w.Write(data)
+}
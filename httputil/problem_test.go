@@ -0,0 +1,201 @@
+package httputil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tsmweb/go-helper-api/cerror"
+	"github.com/tsmweb/go-helper-api/i18n"
+)
+
+func TestRespondWithProblem(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	rec := httptest.NewRecorder()
+	RespondWithProblem(rec, req, cerror.NotFound("user", 42).Wrap(errors.New("sql: no rows")))
+
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != MimeProblemJSON {
+		t.Fatalf("status = %d, Content-Type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"type":     "about:blank",
+		"title":    "Not Found",
+		"status":   float64(404),
+		"detail":   "user 42 not found",
+		"instance": "/users/42",
+		"code":     "not_found",
+		"resource": "user",
+		"id":       float64(42),
+	}
+	for k, v := range want {
+		if body[k] != v {
+			t.Errorf("%s = %v, want %v", k, body[k], v)
+		}
+	}
+	if body["cause"] != nil {
+		t.Errorf("cause = %v, want hidden", body["cause"])
+	}
+}
+
+func TestRespondWithProblem_Validation(t *testing.T) {
+	err := &cerror.ErrValidateModel{Fields: []cerror.FieldError{
+		{Field: "name", Rule: "required", Message: "name is required"},
+		{Field: "email", Rule: "email", Message: `email "a" is invalid`},
+	}}
+
+	rec := httptest.NewRecorder()
+	RespondWithProblem(rec, httptest.NewRequest(http.MethodPost, "/users", nil), err)
+
+	var body struct {
+		Status int                 `json:"status"`
+		Code   string              `json:"code"`
+		Errors []cerror.FieldError `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	if body.Status != http.StatusBadRequest || body.Code != "validation_failed" || len(body.Errors) != 2 {
+		t.Errorf("problem = %+v", body)
+	}
+	if body.Errors[1].Message != `email "a" is invalid` {
+		t.Errorf("errors[1] = %+v", body.Errors[1])
+	}
+}
+
+func TestRespondWithProblem_InternalDetails(t *testing.T) {
+	ProblemTypeBase = "https://api.example.com/problems/"
+	defer func() { ProblemTypeBase = "" }()
+
+	tests := []struct {
+		name   string
+		hook   func(r *http.Request, err error, p *Problem)
+		err    error
+		detail interface{}
+		cause  interface{}
+	}{
+		{"hidden", nil, errors.New("dial tcp 10.0.0.5:5432: connection refused"), nil, nil},
+		{"hidden cause", nil, cerror.Internal(errors.New("open /etc/app.key: permission denied")), nil, nil},
+		{"shown", ShowInternalDetails, errors.New("dial tcp 10.0.0.5:5432: connection refused"),
+			"dial tcp 10.0.0.5:5432: connection refused", nil},
+		{"shown cause", ShowInternalDetails, cerror.Internal(errors.New("open /etc/app.key: permission denied")),
+			"Internal Server Error", "open /etc/app.key: permission denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ProblemHook = tt.hook
+			defer func() { ProblemHook = nil }()
+
+			rec := httptest.NewRecorder()
+			RespondWithProblem(rec, httptest.NewRequest(http.MethodGet, "/", nil), tt.err)
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+
+			if rec.Code != http.StatusInternalServerError || body["detail"] != tt.detail || body["cause"] != tt.cause {
+				t.Errorf("status = %d, detail = %v, cause = %v", rec.Code, body["detail"], body["cause"])
+			}
+			if body["type"] != "https://api.example.com/problems/internal" {
+				t.Errorf("type = %v", body["type"])
+			}
+		})
+	}
+}
+
+func TestRespondWithProblem_ErrorReporter(t *testing.T) {
+	var reports []*cerror.Report
+	ErrorReporter = cerror.ReporterFunc(func(_ context.Context, report *cerror.Report) error {
+		reports = append(reports, report)
+		return nil
+	})
+	defer func() { ErrorReporter = nil }()
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	RespondWithProblem(httptest.NewRecorder(), req, cerror.NotFound("user", 42))
+	RespondWithProblem(httptest.NewRecorder(), req, cerror.Internal(errors.New("connection lost")))
+
+	if len(reports) != 1 || reports[0].Status != http.StatusInternalServerError ||
+		reports[0].Request["path"] != "/users/42" {
+		t.Errorf("reports = %+v, want the internal error only", reports)
+	}
+}
+
+func TestRespondWithProblem_Catalog(t *testing.T) {
+	Catalog = i18n.Default
+	defer func() { Catalog = nil }()
+
+	tests := []struct {
+		language string
+		err      error
+		title    string
+		detail   string
+	}{
+		{"pt-BR,en;q=0.5", cerror.NotFound("usuário", 42), "Não Encontrado", "usuário 42 não encontrado"},
+		{"pt", cerror.ErrUnavailable, "Serviço Indisponível", "Serviço Indisponível"},
+		{"pt-BR", cerror.BadRequest("invalid id"), "Requisição Inválida", "invalid id"},
+		{"fr", cerror.NotFound("user", 42), "Not Found", "user 42 not found"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+		req.Header.Set("Accept-Language", tt.language)
+		rec := httptest.NewRecorder()
+		RespondWithProblem(rec, req, tt.err)
+
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body["title"] != tt.title || body["detail"] != tt.detail {
+			t.Errorf("%s: title = %v, detail = %v, want %q, %q", tt.language, body["title"], body["detail"],
+				tt.title, tt.detail)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	req.Header.Set("Accept-Language", "pt-BR")
+	rec := httptest.NewRecorder()
+	RespondWithProblem(rec, req, &cerror.ErrValidateModel{Fields: []cerror.FieldError{
+		{Field: "name", Rule: "min", Param: "3", Message: "name must be at least 3"},
+		{Field: "cpf", Rule: "cpf", Message: "cpf is not a valid CPF"},
+	}})
+
+	var body struct {
+		Title  string              `json:"title"`
+		Errors []cerror.FieldError `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Header().Get("Content-Language") != "pt-BR" || body.Title != "Falha na Validação" {
+		t.Errorf("Content-Language = %q, title = %q", rec.Header().Get("Content-Language"), body.Title)
+	}
+	if body.Errors[0].Message != "name deve ser no mínimo 3" || body.Errors[1].Message != "cpf is not a valid CPF" {
+		t.Errorf("errors = %+v", body.Errors)
+	}
+}
+
+func TestRespondWithError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	RespondWithError(rec, http.StatusBadRequest, `invalid "name"`)
+
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["error_message"] != `invalid "name"` {
+		t.Errorf("error_message = %q", body["error_message"])
+	}
+}
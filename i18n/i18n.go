@@ -0,0 +1,230 @@
+/*
+Package i18n provides catalogs of localized messages, keyed by the codes of the
+cerror errors and the rules of the validation package, and resolves the locale of
+a request from the Accept-Language header.
+
+The messages may contain parameters in braces, replaced by the metadata of the
+error, as "{resource} {id} não encontrado". The catalogs are loaded from JSON
+files named by the locale, as "pt-BR.json":
+
+	//go:embed locales/*.json
+	var locales embed.FS
+
+	catalog := i18n.NewCatalog("en")
+	if err := catalog.Load(locales, "locales"); err != nil {
+	// ...
+
+	locale := catalog.Match(r.Header.Get("Accept-Language")) // "pt-BR"
+	msg, ok := catalog.Message(locale, "not_found.detail", map[string]interface{}{
+		"resource": "usuário",
+		"id":       42,
+	})
+
+Default is a catalog with the messages in English (en) and Brazilian Portuguese
+(pt-BR), which httputil uses to localize the problem responses:
+
+	httputil.Catalog = i18n.Default
+*/
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Default is the catalog of the messages of the cerror codes and validation rules
+// in English (en) and Brazilian Portuguese (pt-BR), with English as fallback.
+var Default = mustLoad(locales, "locales", "en")
+
+// Catalog holds the localized messages of each locale.
+type Catalog struct {
+	fallbacks []string
+	locales   map[string]string            // lowercase locale -> locale
+	messages  map[string]map[string]string // lowercase locale -> key -> message
+	mu        sync.RWMutex                 // guard locales and messages
+}
+
+// NewCatalog creates an empty Catalog. The fallback locales are searched in order
+// for the messages missing in the requested locale and its parent language.
+func NewCatalog(fallbacks ...string) *Catalog {
+	return &Catalog{
+		fallbacks: fallbacks,
+		locales:   make(map[string]string),
+		messages:  make(map[string]map[string]string),
+	}
+}
+
+// Add adds the messages of the locale, replacing the messages with the same keys.
+func (c *Catalog) Add(locale string, messages map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := strings.ToLower(locale)
+	if c.messages[id] == nil {
+		c.messages[id] = make(map[string]string, len(messages))
+		c.locales[id] = locale
+	}
+	for key, msg := range messages {
+		c.messages[id][key] = msg
+	}
+}
+
+// Load adds the messages of the JSON files of the directory of fsys, as an
+// embed.FS. Each file holds an object of the messages of the locale of its name,
+// as "pt-BR.json".
+func (c *Catalog) Load(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+
+		var messages map[string]string
+		if err = json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("i18n: %s: %w", file, err)
+		}
+		c.Add(strings.TrimSuffix(path.Base(file), ".json"), messages)
+	}
+
+	return nil
+}
+
+// Locales returns the locales of the catalog, sorted.
+func (c *Catalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	locales := make([]string, 0, len(c.locales))
+	for _, locale := range c.locales {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Match returns the locale of the catalog that best matches the Accept-Language
+// header, in order of quality: the same locale, its parent language, as "pt" for
+// "pt-PT", or another locale of the language, as "pt-BR". It returns the first
+// fallback locale when none matches.
+func (c *Catalog) Match(acceptLanguage string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if tag == "*" {
+			break
+		}
+		if locale, ok := c.locales[tag]; ok {
+			return locale
+		}
+		base := parent(tag)
+		if locale, ok := c.locales[base]; ok {
+			return locale
+		}
+		var match string
+		for id, locale := range c.locales {
+			if parent(id) == base && (match == "" || locale < match) {
+				match = locale
+			}
+		}
+		if match != "" {
+			return match
+		}
+	}
+
+	if len(c.fallbacks) > 0 {
+		return c.fallbacks[0]
+	}
+	return ""
+}
+
+var paramRegexp = regexp.MustCompile(`\{(\w+)\}`)
+
+// Message returns the message of the key in the locale, its parent language or
+// the fallback locales, with the parameters replaced. It returns false when the
+// key is not found or a parameter of the message is missing.
+func (c *Catalog) Message(locale, key string, params map[string]interface{}) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	id := strings.ToLower(locale)
+	for _, l := range append([]string{id, parent(id)}, c.fallbacks...) {
+		msg, ok := c.messages[strings.ToLower(l)][key]
+		if !ok {
+			continue
+		}
+
+		complete := true
+		msg = paramRegexp.ReplaceAllStringFunc(msg, func(m string) string {
+			v, ok := params[m[1:len(m)-1]]
+			if !ok {
+				complete = false
+				return m
+			}
+			return fmt.Sprint(v)
+		})
+		return msg, complete
+	}
+
+	return "", false
+}
+
+// parseAcceptLanguage returns the lowercase tags of the header sorted by quality,
+// without the tags of quality 0.
+func parseAcceptLanguage(header string) []string {
+	type tag struct {
+		name    string
+		quality float64
+	}
+
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			if f, err := strconv.ParseFloat(params[2:], 64); err == nil {
+				q = f
+			}
+		}
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" && q > 0 {
+			tags = append(tags, tag{name, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].quality > tags[j].quality })
+
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.name
+	}
+	return names
+}
+
+// parent returns the language of the locale, as "pt" for "pt-br".
+func parent(locale string) string {
+	base, _, _ := strings.Cut(locale, "-")
+	return base
+}
+
+func mustLoad(fsys fs.FS, dir string, fallbacks ...string) *Catalog {
+	c := NewCatalog(fallbacks...)
+	if err := c.Load(fsys, dir); err != nil {
+		panic(err)
+	}
+	return c
+}
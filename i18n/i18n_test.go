@@ -0,0 +1,97 @@
+package i18n
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCatalog_Match(t *testing.T) {
+	c := NewCatalog("en")
+	c.Add("en", map[string]string{})
+	c.Add("pt-BR", map[string]string{})
+	c.Add("es", map[string]string{})
+
+	tests := []struct {
+		header string
+		locale string
+	}{
+		{"", "en"},
+		{"pt-BR", "pt-BR"},
+		{"pt-br,en;q=0.5", "pt-BR"},
+		{"pt-PT", "pt-BR"},
+		{"es-AR,es;q=0.9", "es"},
+		{"fr-FR, pt;q=0.8, en;q=0.9", "en"},
+		{"fr, pt;q=0", "en"},
+		{"*", "en"},
+	}
+
+	for _, tt := range tests {
+		if locale := c.Match(tt.header); locale != tt.locale {
+			t.Errorf("Match(%q) = %q, want %q", tt.header, locale, tt.locale)
+		}
+	}
+}
+
+func TestCatalog_Message(t *testing.T) {
+	c := NewCatalog("en")
+	c.Add("en", map[string]string{"greeting": "Hello, {name}", "bye": "Bye"})
+	c.Add("pt", map[string]string{"greeting": "Olá, {name}"})
+
+	params := map[string]interface{}{"name": "Ana"}
+	tests := []struct {
+		locale string
+		key    string
+		msg    string
+		ok     bool
+	}{
+		{"pt-BR", "greeting", "Olá, Ana", true},
+		{"pt-BR", "bye", "Bye", true},
+		{"fr", "greeting", "Hello, Ana", true},
+		{"en", "unknown", "", false},
+	}
+
+	for _, tt := range tests {
+		if msg, ok := c.Message(tt.locale, tt.key, params); msg != tt.msg || ok != tt.ok {
+			t.Errorf("Message(%q, %q) = %q, %v, want %q, %v", tt.locale, tt.key, msg, ok, tt.msg, tt.ok)
+		}
+	}
+
+	if _, ok := c.Message("en", "greeting", nil); ok {
+		t.Error("Message() without the parameter ok = true")
+	}
+}
+
+func TestCatalog_Load(t *testing.T) {
+	fsys := fstest.MapFS{
+		"locales/en.json":    {Data: []byte(`{"not_found": "Not Found"}`)},
+		"locales/pt-BR.json": {Data: []byte(`{"not_found": "Não Encontrado"}`)},
+	}
+
+	c := NewCatalog("en")
+	if err := c.Load(fsys, "locales"); err != nil {
+		t.Fatal(err)
+	}
+	if locales := c.Locales(); !reflect.DeepEqual(locales, []string{"en", "pt-BR"}) {
+		t.Errorf("Locales() = %v", locales)
+	}
+	if msg, _ := c.Message("pt-BR", "not_found", nil); msg != "Não Encontrado" {
+		t.Errorf("Message() = %q", msg)
+	}
+
+	fsys["locales/es.json"] = &fstest.MapFile{Data: []byte(`{"not_found": 1}`)}
+	if err := c.Load(fsys, "locales"); err == nil {
+		t.Error("Load() with invalid file err = nil")
+	}
+}
+
+func TestDefault(t *testing.T) {
+	if locales := Default.Locales(); !reflect.DeepEqual(locales, []string{"en", "pt-BR"}) {
+		t.Errorf("Locales() = %v", locales)
+	}
+
+	params := map[string]interface{}{"resource": "usuário", "id": 42}
+	if msg, _ := Default.Message("pt-BR", "not_found.detail", params); msg != "usuário 42 não encontrado" {
+		t.Errorf("Message() = %q", msg)
+	}
+}
@@ -43,6 +43,8 @@ package kafka
 import (
 	"context"
 	"time"
+
+	"github.com/tsmweb/go-helper-api/requestid"
 )
 
 // Event structure that represents a Kafka event.
@@ -54,6 +56,16 @@ type Event struct {
 	Time   time.Time
 }
 
+// Context returns a copy of ctx that carries the request ID received in the
+// X-Request-ID header, so that the events produced while handling this event
+// keep the same request ID.
+func (e *Event) Context(ctx context.Context) context.Context {
+	if id, ok := e.Header[requestid.Header]; ok {
+		return requestid.NewContext(ctx, id)
+	}
+	return ctx
+}
+
 // Kafka it is an abstraction to send and consume events from an
 // event kafka service.
 type Kafka interface {
@@ -72,7 +84,8 @@ type Kafka interface {
 type Producer interface {
 	// Publish produces and sends an event for a kafka topic.
 	// The context passed as first argument may also be used to asynchronously
-	// cancel the operation.
+	// cancel the operation. The request ID stored in the context (see package
+	// requestid) is sent in the X-Request-ID header.
 	Publish(ctx context.Context, key []byte, values ...[]byte) error
 
 	// Close flushes pending writes, and waits for all writes to complete before
@@ -2,7 +2,11 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	skafka "github.com/segmentio/kafka-go"
+	"github.com/tsmweb/go-helper-api/cerror"
+	"github.com/tsmweb/go-helper-api/requestid"
+	"io"
 	"log"
 	"time"
 )
@@ -79,19 +83,54 @@ func newProducer(w *skafka.Writer) Producer {
 
 // Publish produces and sends an event for a kafka topic.
 // The context passed as first argument may also be used to asynchronously
-// cancel the operation.
+// cancel the operation. The request ID stored in the context is sent in the
+// X-Request-ID header. Errors that may succeed if retried, as broker leader
+// elections and network timeouts, are reported by cerror.IsRetryable.
 func (p *producer) Publish(ctx context.Context, key []byte, values ...[]byte) error {
 	var messages []skafka.Message
+	var headers []skafka.Header
+
+	if id, ok := requestid.FromContext(ctx); ok {
+		headers = []skafka.Header{{Key: requestid.Header, Value: []byte(id)}}
+	}
 
 	for _, value := range values {
 		message := skafka.Message{
-			Key: key,
-			Value: value,
+			Key:     key,
+			Value:   value,
+			Headers: headers,
 		}
 		messages = append(messages, message)
 	}
 
-	return p.writer.WriteMessages(ctx, messages...)
+	return classify(p.writer.WriteMessages(ctx, messages...))
+}
+
+// classify marks the errors of the brokers that may succeed if retried, as the
+// leader elections, with cerror.Retry. A batch fails as retryable only if all its
+// messages failed with retryable errors.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var writeErrs skafka.WriteErrors
+	if errors.As(err, &writeErrs) {
+		for _, e := range writeErrs {
+			if e != nil && !cerror.IsRetryable(e) {
+				return cerror.Permanent(err)
+			}
+		}
+		return cerror.Retry(err, 0)
+	}
+
+	if errors.Is(err, io.ErrClosedPipe) {
+		return cerror.Permanent(err)
+	}
+	if cerror.IsRetryable(err) {
+		return cerror.Retry(err, 0)
+	}
+	return err
 }
 
 // Close flushes pending writes, and waits for all writes to complete before
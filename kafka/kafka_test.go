@@ -3,8 +3,12 @@ package kafka
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	skafka "github.com/segmentio/kafka-go"
+	"github.com/tsmweb/go-helper-api/cerror"
+	"io"
 	"strings"
 	"testing"
 	"time"
@@ -69,3 +73,29 @@ func (u *user) toJSON() []byte {
 	}
 	return us
 }
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"leader not available", skafka.LeaderNotAvailable, true},
+		{"not leader", skafka.NotLeaderForPartition, true},
+		{"message too large", skafka.MessageSizeTooLarge, false},
+		{"closed", io.ErrClosedPipe, false},
+		{"batch retryable", skafka.WriteErrors{nil, skafka.LeaderNotAvailable}, true},
+		{"batch permanent", skafka.WriteErrors{skafka.LeaderNotAvailable, skafka.MessageSizeTooLarge}, false},
+		{"timeout", context.DeadlineExceeded, true},
+	}
+
+	for _, tt := range tests {
+		err := classify(tt.err)
+		if cerror.IsRetryable(err) != tt.retryable {
+			t.Errorf("%s: IsRetryable() = %v, want %v", tt.name, !tt.retryable, tt.retryable)
+		}
+		if !errors.Is(err, tt.err) && !errors.As(err, new(skafka.WriteErrors)) {
+			t.Errorf("%s: the error was not wrapped: %v", tt.name, err)
+		}
+	}
+}
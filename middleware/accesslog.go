@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/auth"
+	"github.com/tsmweb/go-helper-api/observability/event"
+	"github.com/tsmweb/go-helper-api/requestid"
+)
+
+// AccessLogOptions configures the AccessLog middleware.
+type AccessLogOptions struct {
+	// Writer receives the records as JSON lines.
+	Writer io.Writer
+
+	// SendEvents sends the records as events (see observability/event). The type
+	// of the event is info, warning for 4xx responses and error for 5xx responses.
+	SendEvents bool
+
+	// Host is reported in the events. Default os.Hostname().
+	Host string
+
+	// JWT extracts the subject of the request from the token, when present.
+	JWT auth.JWT
+
+	// SubjectClaim is the token claim that identifies the subject. Default "sub".
+	SubjectClaim string
+
+	// ExcludePaths is the list of paths not logged. A path ending with "*"
+	// excludes all the paths with its prefix, as in "/static/*".
+	ExcludePaths []string
+
+	// SampleRate is the fraction of the requests logged, between 0 and 1.
+	// Responses with status 5xx are always logged. Default 1.
+	SampleRate float64
+}
+
+// AccessLogRecord represents the access log of a request.
+type AccessLogRecord struct {
+	Time      string  `json:"time"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Query     string  `json:"query,omitempty"`
+	Status    int     `json:"status"`
+	Bytes     int64   `json:"bytes"`
+	LatencyMS float64 `json:"latency_ms"`
+	ClientIP  string  `json:"client_ip"`
+	UserAgent string  `json:"user_agent,omitempty"`
+	Subject   string  `json:"subject,omitempty"`
+	RequestID string  `json:"request_id,omitempty"`
+}
+
+func (a AccessLogRecord) toJSON() []byte {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// NewAccessLog creates a middleware that logs the method, path, status, size,
+// latency, client IP, subject and request ID of the requests.
+func NewAccessLog(opts AccessLogOptions) Middleware {
+	if opts.Host == "" {
+		opts.Host, _ = os.Hostname()
+	}
+	if opts.SubjectClaim == "" {
+		opts.SubjectClaim = "sub"
+	}
+	if opts.SampleRate <= 0 || opts.SampleRate > 1 {
+		opts.SampleRate = 1
+	}
+
+	l := &accessLog{opts: opts}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if l.excluded(r.URL.Path) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			rw := newResponseWriter(w)
+			h.ServeHTTP(rw.writer(), r)
+
+			if rw.Status() < http.StatusInternalServerError && opts.SampleRate < 1 &&
+				rand.Float64() >= opts.SampleRate {
+				return
+			}
+
+			l.log(r, l.record(r, rw, start))
+		})
+	}
+}
+
+type accessLog struct {
+	opts AccessLogOptions
+	mu   sync.Mutex // guard opts.Writer
+}
+
+func (l *accessLog) excluded(path string) bool {
+	for _, p := range l.opts.ExcludePaths {
+		if strings.HasSuffix(p, "*") {
+			if strings.HasPrefix(path, p[:len(p)-1]) {
+				return true
+			}
+		} else if path == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *accessLog) record(r *http.Request, rw *responseWriter, start time.Time) AccessLogRecord {
+	record := AccessLogRecord{
+		Time:      start.Format("2006-01-02T15:04:05-0700"), // yyyy-MM-dd'T'HH:mm:ssZ
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Query:     r.URL.RawQuery,
+		Status:    rw.Status(),
+		Bytes:     rw.bytes,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		ClientIP:  ClientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+
+	record.RequestID, _ = requestid.FromContext(r.Context())
+	if record.RequestID == "" {
+		record.RequestID = rw.Header().Get(requestid.Header)
+	}
+
+	if l.opts.JWT != nil {
+		if sub, err := l.opts.JWT.GetDataToken(r, l.opts.SubjectClaim); err == nil && sub != nil {
+			record.Subject = fmt.Sprint(sub)
+		}
+	}
+
+	return record
+}
+
+func (l *accessLog) log(r *http.Request, record AccessLogRecord) {
+	data := record.toJSON()
+
+	if l.opts.Writer != nil {
+		l.mu.Lock()
+		l.opts.Writer.Write(append(data, '\n'))
+		l.mu.Unlock()
+	}
+
+	if l.opts.SendEvents {
+		eventType := event.Info
+		switch {
+		case record.Status >= http.StatusInternalServerError:
+			eventType = event.Error
+		case record.Status >= http.StatusBadRequest:
+			eventType = event.Warning
+		}
+
+		title := fmt.Sprintf("%s %s %d", record.Method, record.Path, record.Status)
+		e := event.New(l.opts.Host, record.Subject, title, eventType, string(data))
+		e.RequestID = record.RequestID
+		_ = event.TrySend(e)
+	}
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// hijackRecorder is an httptest.ResponseRecorder that implements http.Hijacker.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (h hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+// plainWriter is an http.ResponseWriter without optional interfaces.
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func TestAccessLog_OptionalInterfaces(t *testing.T) {
+	tests := []struct {
+		name     string
+		w        http.ResponseWriter
+		flusher  bool
+		hijacker bool
+	}{
+		{"flusher", httptest.NewRecorder(), true, false},
+		{"flusher and hijacker", hijackRecorder{httptest.NewRecorder()}, true, true},
+		{"none", plainWriter{httptest.NewRecorder()}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewAccessLog(AccessLogOptions{Writer: io.Discard})(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					if _, ok := w.(http.Flusher); ok != tt.flusher {
+						t.Errorf("http.Flusher = %v, want %v", ok, tt.flusher)
+					}
+					if _, ok := w.(http.Hijacker); ok != tt.hijacker {
+						t.Errorf("http.Hijacker = %v, want %v", ok, tt.hijacker)
+					}
+					if u, ok := w.(interface{ Unwrap() http.ResponseWriter }); !ok || u.Unwrap() != tt.w {
+						t.Error("Unwrap() does not return the original http.ResponseWriter")
+					}
+				}))
+			h.ServeHTTP(tt.w, httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewChain(NewAccessLog(AccessLogOptions{
+		Writer:       &buf,
+		ExcludePaths: []string{"/health", "/static/*"},
+	}), RequestID).ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})
+
+	for _, path := range []string{"/health", "/static/app.js", "/users?page=2"} {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var record AccessLogRecord
+	dec := json.NewDecoder(&buf)
+	if err := dec.Decode(&record); err != nil {
+		t.Fatal(err)
+	}
+	if dec.More() {
+		t.Error("excluded paths were logged")
+	}
+
+	if record.Method != http.MethodPost || record.Path != "/users" || record.Query != "page=2" {
+		t.Errorf("request = %s %s?%s", record.Method, record.Path, record.Query)
+	}
+	if record.Status != http.StatusCreated || record.Bytes != int64(len("created")) {
+		t.Errorf("status = %d, bytes = %d", record.Status, record.Bytes)
+	}
+	if record.ClientIP != "10.0.0.1" {
+		t.Errorf("client IP = %q", record.ClientIP)
+	}
+	if record.RequestID == "" {
+		t.Error("request ID not logged")
+	}
+}
@@ -1,31 +1,221 @@
 package middleware
 
 import (
-	"github.com/tsmweb/go-helper-api/auth"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang-jwt/jwt/v4/request"
+	"github.com/tsmweb/go-helper-api/auth"
+	"github.com/tsmweb/go-helper-api/httputil"
+	"github.com/tsmweb/go-helper-api/observability/event"
 )
 
+// AuthFailure represents the reason why a request was not authenticated
+// ("missing", "expired", "bad_signature", ...).
+type AuthFailure int
+
+const (
+	// AuthFailureMissing represents a request without token.
+	AuthFailureMissing AuthFailure = iota
+
+	// AuthFailureMalformed represents a token that could not be parsed.
+	AuthFailureMalformed
+
+	// AuthFailureBadSignature represents a token with an invalid signature.
+	AuthFailureBadSignature
+
+	// AuthFailureExpired represents an expired token.
+	AuthFailureExpired
+
+	// AuthFailureRevoked represents a token revoked before its expiration.
+	AuthFailureRevoked
+
+	// AuthFailureInvalid represents a token rejected for any other reason.
+	AuthFailureInvalid
+)
+
+var authFailureText = map[AuthFailure]string{
+	AuthFailureMissing:      "missing",
+	AuthFailureMalformed:    "malformed",
+	AuthFailureBadSignature: "bad_signature",
+	AuthFailureExpired:      "expired",
+	AuthFailureRevoked:      "revoked",
+	AuthFailureInvalid:      "invalid",
+}
+
+// String return the name of the AuthFailure.
+func (f AuthFailure) String() string {
+	return authFailureText[f]
+}
+
+// AuthOptions configures the Auth middleware.
+type AuthOptions struct {
+	// Host is reported in the audit events. Default os.Hostname().
+	Host string
+
+	// SubjectClaim is the token claim that identifies the subject. Default "sub".
+	SubjectClaim string
+
+	// IsRevoked reports whether a valid token has been revoked.
+	IsRevoked func(token string) bool
+
+	// MaxFailures is the number of failures per IP or subject allowed within
+	// FailureWindow before they are blocked. A blocked IP is rejected before the
+	// token is verified, even if valid. Only the tokens with a valid signature,
+	// as the expired or revoked ones, count and are blocked for the subject, so
+	// a forged subject can not lock out its owner. Zero disables the lockout.
+	MaxFailures int
+
+	// FailureWindow is the period in which failures are counted. Default 1 minute.
+	FailureWindow time.Duration
+
+	// LockoutDuration is the period of the first block. Each new block of the same
+	// IP or subject doubles it, up to MaxLockoutDuration. Default 1 minute.
+	LockoutDuration time.Duration
+
+	// MaxLockoutDuration is the maximum period of a block. Default 1 hour.
+	MaxLockoutDuration time.Duration
+}
+
 // Auth validates HTTP requests via token.
 type Auth interface {
 	RequireTokenAuth(w http.ResponseWriter, r *http.Request, next http.HandlerFunc)
 }
 
 type _auth struct {
-	jwt auth.JWT
+	jwt      auth.JWT
+	opts     AuthOptions
+	throttle *throttle
 }
 
 // NewAuth creates a new instance of Auth and receives an jwt.JWT object as a parameter.
 func NewAuth(jwt auth.JWT) Auth {
-	return &_auth{jwt}
+	return NewAuthWithOptions(jwt, AuthOptions{})
+}
+
+// NewAuthWithOptions creates a new instance of Auth configured by AuthOptions.
+func NewAuthWithOptions(jwt auth.JWT, opts AuthOptions) Auth {
+	if opts.Host == "" {
+		opts.Host, _ = os.Hostname()
+	}
+	if opts.SubjectClaim == "" {
+		opts.SubjectClaim = "sub"
+	}
+	if opts.FailureWindow <= 0 {
+		opts.FailureWindow = time.Minute
+	}
+	if opts.LockoutDuration <= 0 {
+		opts.LockoutDuration = time.Minute
+	}
+	if opts.MaxLockoutDuration <= 0 {
+		opts.MaxLockoutDuration = time.Hour
+	}
+
+	return &_auth{
+		jwt:  jwt,
+		opts: opts,
+		throttle: newThrottle(opts.MaxFailures, opts.FailureWindow, opts.LockoutDuration,
+			opts.MaxLockoutDuration),
+	}
 }
 
 // RequireTokenAuth performs the middleware function by extracting and validating the request token, if the
 // token is valid, the request will follow its flow, if the token is invalid, the unauthorized response will be sent.
+// Every failure is sent as a warning event, and IPs or subjects that exceed the failures allowed receive
+// the too many requests response until the lockout expires.
 func (a *_auth) RequireTokenAuth(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	_, err := a.jwt.ExtractToken(r)
+	ip := ClientIP(r)
+	if wait, blocked := a.throttle.blocked("ip:" + ip); blocked {
+		a.tooManyRequests(w, wait)
+		return
+	}
+
+	token, err := a.jwt.ExtractToken(r)
+	if err == nil && a.opts.IsRevoked != nil && a.opts.IsRevoked(token) {
+		err = errTokenRevoked
+	}
+
+	if err == nil && a.opts.MaxFailures <= 0 {
+		next(w, r)
+		return
+	}
+
+	// the subject of a token without a valid signature may be forged to lock out
+	// its owner, so only the tokens verified are counted for the subject.
+	subject := a.subject(r)
+	failure := authFailureOf(err)
+	keys := []string{"ip:" + ip}
+	if subject != "" && (err == nil || failure == AuthFailureExpired || failure == AuthFailureRevoked) {
+		keys = append(keys, "sub:"+subject)
+	}
+
+	// the IP is already checked.
+	if wait, blocked := a.throttle.blocked(keys[1:]...); blocked {
+		a.tooManyRequests(w, wait)
+		return
+	}
+
 	if err == nil {
 		next(w, r)
-	} else {
-		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	a.audit(r, ip, subject, "Authentication failure", failure.String())
+
+	if a.throttle.fail(keys...) {
+		a.audit(r, ip, subject, "Authentication lockout", failure.String())
+	}
+
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+// subject returns the unverified subject of the token, used only to identify
+// the failures.
+func (a *_auth) subject(r *http.Request) string {
+	claims, err := auth.ParseUnverified(r)
+	if err != nil {
+		return ""
+	}
+
+	sub, ok := claims[a.opts.SubjectClaim]
+	if !ok || sub == nil {
+		return ""
+	}
+	return fmt.Sprint(sub)
+}
+
+func (a *_auth) tooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", seconds(wait))
+	httputil.RespondWithError(w, http.StatusTooManyRequests, http.StatusText(http.StatusTooManyRequests))
+}
+
+func (a *_auth) audit(r *http.Request, ip, subject, title, reason string) {
+	detail := fmt.Sprintf("reason=%s ip=%s method=%s path=%s", reason, ip, r.Method, r.URL.Path)
+	_ = event.TrySend(event.NewWithContext(r.Context(), a.opts.Host, subject, title, event.Warning, detail))
+}
+
+var errTokenRevoked = errors.New("token is revoked")
+
+// authFailureOf classifies the error returned when extracting the token. An
+// invalid signature prevails over the other errors of the claims, so an expired
+// token has a valid signature.
+func authFailureOf(err error) AuthFailure {
+	switch {
+	case errors.Is(err, request.ErrNoTokenInRequest):
+		return AuthFailureMissing
+	case errors.Is(err, errTokenRevoked):
+		return AuthFailureRevoked
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return AuthFailureBadSignature
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return AuthFailureExpired
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return AuthFailureMalformed
+	default:
+		return AuthFailureInvalid
 	}
 }
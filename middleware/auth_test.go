@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang-jwt/jwt/v4/request"
+)
+
+// fakeJWT implements auth.JWT returning the configured error.
+type fakeJWT struct {
+	err error
+}
+
+func (f fakeJWT) GenerateToken(payload map[string]interface{}, exp int) (string, error) {
+	return "", nil
+}
+
+func (f fakeJWT) ExtractToken(r *http.Request) (string, error) {
+	return "token", f.err
+}
+
+func (f fakeJWT) GetDataToken(r *http.Request, key string) (interface{}, error) {
+	return nil, f.err
+}
+
+func TestAuth_authFailureOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want AuthFailure
+	}{
+		{request.ErrNoTokenInRequest, AuthFailureMissing},
+		{&jwt.ValidationError{Errors: jwt.ValidationErrorExpired}, AuthFailureExpired},
+		{&jwt.ValidationError{Errors: jwt.ValidationErrorSignatureInvalid}, AuthFailureBadSignature},
+		{&jwt.ValidationError{Errors: jwt.ValidationErrorExpired | jwt.ValidationErrorSignatureInvalid},
+			AuthFailureBadSignature},
+		{&jwt.ValidationError{Errors: jwt.ValidationErrorMalformed}, AuthFailureMalformed},
+		{errTokenRevoked, AuthFailureRevoked},
+		{errors.New("invalid token"), AuthFailureInvalid},
+	}
+
+	for _, tt := range tests {
+		if got := authFailureOf(tt.err); got != tt.want {
+			t.Errorf("authFailureOf(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestAuth_RequireTokenAuth(t *testing.T) {
+	next := func(w http.ResponseWriter, r *http.Request) {}
+
+	a := NewAuthWithOptions(fakeJWT{}, AuthOptions{
+		IsRevoked: func(token string) bool { return token == "token" },
+	})
+	rec := httptest.NewRecorder()
+	a.RequireTokenAuth(rec, httptest.NewRequest(http.MethodGet, "/", nil), next)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	a = NewAuthWithOptions(fakeJWT{}, AuthOptions{})
+	rec = httptest.NewRecorder()
+	a.RequireTokenAuth(rec, httptest.NewRequest(http.MethodGet, "/", nil), next)
+	if rec.Code != http.StatusOK {
+		t.Errorf("valid token: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestAuth_Lockout(t *testing.T) {
+	a := NewAuthWithOptions(tokenJWT{"": request.ErrNoTokenInRequest}, AuthOptions{MaxFailures: 2})
+
+	request := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		a.RequireTokenAuth(rec, req, func(w http.ResponseWriter, r *http.Request) {})
+		return rec.Code
+	}
+
+	status := make([]int, 0, 4)
+	for _, token := range []string{"", "", "", "valid"} {
+		status = append(status, request(token))
+	}
+
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests,
+		http.StatusTooManyRequests}
+	if fmt.Sprint(status) != fmt.Sprint(want) {
+		t.Errorf("status = %v, want %v", status, want)
+	}
+}
+
+// tokenJWT implements auth.JWT returning the error of each bearer token.
+type tokenJWT map[string]error
+
+func (f tokenJWT) GenerateToken(payload map[string]interface{}, exp int) (string, error) {
+	return "", nil
+}
+
+func (f tokenJWT) ExtractToken(r *http.Request) (string, error) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token, f[token]
+}
+
+func (f tokenJWT) GetDataToken(r *http.Request, key string) (interface{}, error) {
+	return nil, nil
+}
+
+func TestAuth_LockoutForgedSubject(t *testing.T) {
+	sign := func(key string, exp int64) string {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "victim", "exp": exp}).
+			SignedString([]byte(key))
+		return token
+	}
+	forged := sign("attacker", time.Now().Add(time.Hour).Unix())
+	expired := sign("secret", time.Now().Add(-time.Hour).Unix())
+	valid := sign("secret", time.Now().Add(time.Hour).Unix())
+
+	a := NewAuthWithOptions(tokenJWT{
+		forged:  &jwt.ValidationError{Errors: jwt.ValidationErrorSignatureInvalid},
+		expired: &jwt.ValidationError{Errors: jwt.ValidationErrorExpired},
+	}, AuthOptions{MaxFailures: 2})
+
+	request := func(token, ip string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		a.RequireTokenAuth(rec, req, func(w http.ResponseWriter, r *http.Request) {})
+		return rec.Code
+	}
+
+	for _, ip := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+		if code := request(forged, ip); code != http.StatusUnauthorized {
+			t.Errorf("forged token from %s: status = %d, want %d", ip, code, http.StatusUnauthorized)
+		}
+	}
+	if code := request(valid, "198.51.100.4"); code != http.StatusOK {
+		t.Errorf("valid token after forged ones: status = %d, want %d", code, http.StatusOK)
+	}
+
+	status := make([]int, 0, 3)
+	for _, ip := range []string{"198.51.100.5", "198.51.100.6", "198.51.100.7"} {
+		status = append(status, request(expired, ip))
+	}
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	if fmt.Sprint(status) != fmt.Sprint(want) {
+		t.Errorf("expired tokens: status = %v, want %v", status, want)
+	}
+	if code := request(valid, "198.51.100.8"); code != http.StatusTooManyRequests {
+		t.Errorf("valid token of a blocked subject: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
+
+func TestThrottle_Backoff(t *testing.T) {
+	th := newThrottle(1, time.Minute, time.Second, 3*time.Second)
+
+	want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}
+	for i, w := range want {
+		if got := th.lockoutFor(i); got != w {
+			t.Errorf("lockoutFor(%d) = %v, want %v", i, got, w)
+		}
+	}
+}
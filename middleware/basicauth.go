@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/cerror"
+	"github.com/tsmweb/go-helper-api/httputil"
+	"github.com/tsmweb/go-helper-api/util/hashutil"
+)
+
+// CredentialStore provides the password hashes used by BasicAuth.
+type CredentialStore interface {
+	// PasswordHash returns the bcrypt hash of the user's password, as generated by
+	// hashutil.HashBcrypt. It returns cerror.ErrNotFound when the user does not exist.
+	PasswordHash(username string) (string, error)
+}
+
+// Credentials is an in-memory CredentialStore that maps the username to the
+// bcrypt hash of its password.
+type Credentials map[string]string
+
+// PasswordHash implements interface CredentialStore.
+func (c Credentials) PasswordHash(username string) (string, error) {
+	hash, ok := c[username]
+	if !ok {
+		return "", cerror.ErrNotFound
+	}
+	return hash, nil
+}
+
+// BasicAuthOptions configures the BasicAuth middleware.
+type BasicAuthOptions struct {
+	// Realm is sent in the WWW-Authenticate header. Default "Restricted".
+	Realm string
+
+	// MaxFailures is the number of failed attempts per username or IP allowed
+	// within FailureWindow before they are blocked. Default 5, a negative
+	// value disables the throttling.
+	MaxFailures int
+
+	// FailureWindow is the period in which failed attempts are counted. Default 1 minute.
+	FailureWindow time.Duration
+
+	// LockoutDuration is the period a username or IP stays blocked. Default 5 minutes.
+	LockoutDuration time.Duration
+}
+
+// BasicAuth validates HTTP requests via HTTP Basic authentication.
+type BasicAuth interface {
+	RequireBasicAuth(w http.ResponseWriter, r *http.Request, next http.HandlerFunc)
+}
+
+type _basicAuth struct {
+	store     CredentialStore
+	challenge string
+	throttle  *throttle
+}
+
+// NewBasicAuth creates a new instance of BasicAuth that validates the credentials
+// against the CredentialStore.
+func NewBasicAuth(store CredentialStore, opts BasicAuthOptions) BasicAuth {
+	if opts.Realm == "" {
+		opts.Realm = "Restricted"
+	}
+	if opts.MaxFailures == 0 {
+		opts.MaxFailures = 5
+	}
+	if opts.FailureWindow <= 0 {
+		opts.FailureWindow = time.Minute
+	}
+	if opts.LockoutDuration <= 0 {
+		opts.LockoutDuration = 5 * time.Minute
+	}
+
+	lockout := opts.LockoutDuration
+
+	return &_basicAuth{
+		store:     store,
+		challenge: fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, opts.Realm),
+		throttle:  newThrottle(opts.MaxFailures, opts.FailureWindow, lockout, lockout),
+	}
+}
+
+// RequireBasicAuth performs the middleware function by validating the credentials sent
+// in the Authorization header. If they are valid, the request will follow its flow with
+// the username stored in its context, otherwise the unauthorized response will be sent.
+// Usernames and IPs that exceed the failed attempts are answered with too many requests.
+func (a *_basicAuth) RequireBasicAuth(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		a.unauthorized(w)
+		return
+	}
+
+	keys := []string{"user:" + username, "ip:" + ClientIP(r)}
+
+	if wait, blocked := a.throttle.blocked(keys...); blocked {
+		a.tooManyRequests(w, wait)
+		return
+	}
+
+	valid, err := a.verify(username, password)
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	if !valid {
+		a.throttle.fail(keys...)
+		a.unauthorized(w)
+		return
+	}
+
+	// the IP is not reset, as an attacker with a valid account could clear it while
+	// guessing the passwords of other users.
+	a.throttle.reset(keys[0])
+	ctx := context.WithValue(r.Context(), basicAuthUserKey, username)
+	next(w, r.WithContext(ctx))
+}
+
+// verify compares the password with its bcrypt hash. Unknown users are compared
+// against a dummy hash so that the response time does not reveal them.
+func (a *_basicAuth) verify(username, password string) (bool, error) {
+	found := true
+	hash, err := a.store.PasswordHash(username)
+	if err != nil {
+		if !errors.Is(err, cerror.ErrNotFound) {
+			return false, err
+		}
+		found = false
+		hash = dummyPasswordHash
+	}
+
+	match, err := hashutil.VerifyBcrypt(hash, password)
+	if err != nil {
+		return false, err
+	}
+	return found && match, nil
+}
+
+func (a *_basicAuth) unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", a.challenge)
+	httputil.RespondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+}
+
+func (a *_basicAuth) tooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", seconds(wait))
+	httputil.RespondWithError(w, http.StatusTooManyRequests, http.StatusText(http.StatusTooManyRequests))
+}
+
+// BasicAuthUser returns the username authenticated by BasicAuth.
+func BasicAuthUser(ctx context.Context) (string, bool) {
+	username, ok := ctx.Value(basicAuthUserKey).(string)
+	return username, ok
+}
+
+// dummyPasswordHash is a bcrypt hash with the default cost, compared against the
+// passwords of unknown users.
+const dummyPasswordHash = "$2a$10$XzG7x6xOFfjesUtjSBOL7.d2UzXoPB7HGHmr5aXOhwlCEArb5XpKm"
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tsmweb/go-helper-api/util/hashutil"
+)
+
+func TestBasicAuth_RequireBasicAuth(t *testing.T) {
+	hash, _ := hashutil.HashBcrypt("secret")
+	basicAuth := NewBasicAuth(Credentials{"admin": hash}, BasicAuthOptions{Realm: "metrics"})
+
+	next := func(w http.ResponseWriter, r *http.Request) {
+		username, _ := BasicAuthUser(r.Context())
+		w.Write([]byte(username))
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		noAuth   bool
+		status   int
+	}{
+		{"valid credentials", "admin", "secret", false, http.StatusOK},
+		{"invalid password", "admin", "wrong", false, http.StatusUnauthorized},
+		{"unknown user", "guest", "secret", false, http.StatusUnauthorized},
+		{"missing credentials", "", "", true, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if !tt.noAuth {
+				req.SetBasicAuth(tt.username, tt.password)
+			}
+			rec := httptest.NewRecorder()
+
+			basicAuth.RequireBasicAuth(rec, req, next)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusUnauthorized &&
+				rec.Header().Get("WWW-Authenticate") != `Basic realm="metrics", charset="UTF-8"` {
+				t.Errorf("WWW-Authenticate = %q", rec.Header().Get("WWW-Authenticate"))
+			}
+			if tt.status == http.StatusOK && rec.Body.String() != tt.username {
+				t.Errorf("user = %q, want %q", rec.Body.String(), tt.username)
+			}
+		})
+	}
+}
+
+func TestBasicAuth_Throttle(t *testing.T) {
+	hash, _ := hashutil.HashBcrypt("secret")
+	basicAuth := NewBasicAuth(Credentials{"admin": hash}, BasicAuthOptions{MaxFailures: 3})
+	next := func(w http.ResponseWriter, r *http.Request) {}
+
+	do := func(password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth("admin", password)
+		rec := httptest.NewRecorder()
+		basicAuth.RequireBasicAuth(rec, req, next)
+		return rec
+	}
+
+	for i := 0; i < 3; i++ {
+		if rec := do("wrong"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status = %d, want %d", i, rec.Code, http.StatusUnauthorized)
+		}
+	}
+
+	rec := do("secret")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After header not set")
+	}
+}
+
+func TestBasicAuth_ThrottleIPNotReset(t *testing.T) {
+	hash, _ := hashutil.HashBcrypt("secret")
+	basicAuth := NewBasicAuth(Credentials{"admin": hash}, BasicAuthOptions{MaxFailures: 3})
+	next := func(w http.ResponseWriter, r *http.Request) {}
+
+	do := func(username, password string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(username, password)
+		rec := httptest.NewRecorder()
+		basicAuth.RequireBasicAuth(rec, req, next)
+		return rec.Code
+	}
+
+	// the valid logins of admin do not reset the failures of the IP.
+	status := []int{
+		do("guest1", "wrong"),
+		do("admin", "secret"),
+		do("guest2", "wrong"),
+		do("admin", "secret"),
+		do("guest3", "wrong"),
+		do("guest4", "wrong"),
+	}
+
+	want := []int{http.StatusUnauthorized, http.StatusOK, http.StatusUnauthorized, http.StatusOK,
+		http.StatusUnauthorized, http.StatusTooManyRequests}
+	if fmt.Sprint(status) != fmt.Sprint(want) {
+		t.Errorf("status = %v, want %v", status, want)
+	}
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/tsmweb/go-helper-api/httputil"
+)
+
+// BodyLimitOptions configures the BodyLimit middleware.
+type BodyLimitOptions struct {
+	// MaxSize is the maximum size in bytes of the request body. Default 1 MB.
+	MaxSize int64
+
+	// RouteMaxSize is the maximum size of the body by path, overriding MaxSize. A
+	// path ending with "*" applies to all the paths with its prefix, as in
+	// "/upload/*"; the longest prefix wins.
+	RouteMaxSize map[string]int64
+
+	// AllowedTypes is the list of content types accepted in the requests with a
+	// body. A request without Content-Type is taken as
+	// httputil.MimeApplicationOctetStream. Default all the content types.
+	AllowedTypes []httputil.MimeType
+}
+
+// NewBodyLimit creates a middleware that limits the size of the request body and
+// the accepted content types. Requests declaring a larger Content-Length are
+// answered with request entity too large before reaching the handler; requests
+// whose body exceeds the limit while read by the handler, with
+// http.MaxBytesReader, are answered with request entity too large in place of
+// the response of the handler. Requests with a content type not allowed are
+// answered with unsupported media type.
+func NewBodyLimit(opts BodyLimitOptions) Middleware {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = 1 << 20
+	}
+
+	var accepted []string
+	for _, mimeType := range opts.AllowedTypes {
+		accepted = append(accepted, mimeType.String())
+	}
+	unsupported := "unsupported content type, accepted: " + strings.Join(accepted, ", ")
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength == 0 || r.Body == nil || r.Body == http.NoBody {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			if len(opts.AllowedTypes) > 0 && !hasAnyContentType(r, opts.AllowedTypes) {
+				httputil.RespondWithError(w, http.StatusUnsupportedMediaType, unsupported)
+				return
+			}
+
+			maxSize := routeMaxSize(opts.RouteMaxSize, r.URL.Path, opts.MaxSize)
+			if r.ContentLength > maxSize {
+				respondTooLarge(w)
+				return
+			}
+
+			body := &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, maxSize)}
+			r.Body = body
+			bw := &bodyLimitWriter{responseWriter: newResponseWriter(w), body: body}
+			h.ServeHTTP(exposeInterfaces(bw, w), r)
+
+			if body.exceeded && !bw.Written() {
+				bw.reject()
+			}
+		})
+	}
+}
+
+func hasAnyContentType(r *http.Request, mimeTypes []httputil.MimeType) bool {
+	for _, mimeType := range mimeTypes {
+		if httputil.HasContentType(r, mimeType) {
+			return true
+		}
+	}
+	return false
+}
+
+// routeMaxSize returns the maximum size of the route matching the path.
+func routeMaxSize(routes map[string]int64, path string, maxSize int64) int64 {
+	if size, ok := routes[path]; ok {
+		return size
+	}
+
+	longest := -1
+	for route, size := range routes {
+		prefix := strings.TrimSuffix(route, "*")
+		if prefix != route && strings.HasPrefix(path, prefix) && len(prefix) > longest {
+			longest = len(prefix)
+			maxSize = size
+		}
+	}
+	return maxSize
+}
+
+func respondTooLarge(w http.ResponseWriter) {
+	httputil.RespondWithError(w, http.StatusRequestEntityTooLarge, "request body too large")
+}
+
+// limitedBody records whether the request body exceeded the limit.
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+// Read implements interface io.Reader.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// bodyLimitWriter replaces the response of the handler with request entity too
+// large when the request body exceeded the limit.
+type bodyLimitWriter struct {
+	*responseWriter
+	body     *limitedBody
+	rejected bool
+}
+
+// WriteHeader implements interface http.ResponseWriter.
+func (bw *bodyLimitWriter) WriteHeader(status int) {
+	if bw.body.exceeded && !bw.Written() {
+		bw.reject()
+	}
+	if bw.rejected {
+		return
+	}
+	bw.responseWriter.WriteHeader(status)
+}
+
+// Write implements interface http.ResponseWriter.
+func (bw *bodyLimitWriter) Write(p []byte) (int, error) {
+	if bw.body.exceeded && !bw.Written() {
+		bw.reject()
+	}
+	if bw.rejected {
+		return len(p), nil
+	}
+	return bw.responseWriter.Write(p)
+}
+
+func (bw *bodyLimitWriter) reject() {
+	bw.Header().Del("Content-Length")
+	respondTooLarge(bw.responseWriter)
+	bw.rejected = true
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tsmweb/go-helper-api/httputil"
+)
+
+func TestBodyLimit(t *testing.T) {
+	h := NewBodyLimit(BodyLimitOptions{
+		MaxSize:      10,
+		RouteMaxSize: map[string]int64{"/upload/*": 100, "/upload/small": 5},
+		AllowedTypes: []httputil.MimeType{httputil.MimeApplicationJSON, httputil.MimeImagePNG},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			httputil.RespondWithError(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	tests := []struct {
+		name          string
+		path          string
+		contentType   string
+		body          string
+		unknownLength bool
+		status        int
+	}{
+		{"allowed", "/users", "application/json", `{"a":1}`, false, http.StatusCreated},
+		{"charset", "/users", "application/json; charset=utf-8", `{"a":1}`, false, http.StatusCreated},
+		{"too large", "/users", "application/json", `{"name":"too large"}`, false, http.StatusRequestEntityTooLarge},
+		{"too large unknown length", "/users", "application/json", `{"name":"too large"}`, true,
+			http.StatusRequestEntityTooLarge},
+		{"route", "/upload/photo", "application/json", `{"name":"too large"}`, false, http.StatusCreated},
+		{"exact route", "/upload/small", "application/json", `{"a":1}`, false, http.StatusRequestEntityTooLarge},
+		{"unsupported", "/users", "text/plain", "a", false, http.StatusUnsupportedMediaType},
+		{"no content type", "/users", "", "a", false, http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.unknownLength {
+				req.ContentLength = -1
+				req.Body = io.NopCloser(req.Body)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.status >= http.StatusBadRequest &&
+				rec.Header().Get("Content-Type") != httputil.MimeTypeText(httputil.MimeApplicationJSON) {
+				t.Errorf("Content-Type = %q, want JSON error", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if rec.Code != http.StatusBadRequest { // the handler received the request without body.
+		t.Errorf("GET: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,476 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/auth"
+)
+
+// CachedResponse is a response stored by the Cache.
+type CachedResponse struct {
+	Status               int
+	Header               http.Header
+	Body                 []byte
+	StoredAt             time.Time
+	MaxAge               time.Duration
+	StaleWhileRevalidate time.Duration
+
+	// Shared reports whether the response may be served to the requests with
+	// credentials of another user, as marked by the public or s-maxage directives.
+	Shared bool
+}
+
+// age returns the time elapsed since the response was stored.
+func (c *CachedResponse) age(now time.Time) time.Duration {
+	return now.Sub(c.StoredAt)
+}
+
+// size returns the approximate size in bytes of the response.
+func (c *CachedResponse) size() int64 {
+	size := int64(len(c.Body))
+	for k, v := range c.Header {
+		size += int64(len(k))
+		for _, s := range v {
+			size += int64(len(s))
+		}
+	}
+	return size
+}
+
+// CacheStore keeps the cached responses.
+type CacheStore interface {
+	// Get returns the response stored for the key.
+	Get(key string) (*CachedResponse, bool)
+
+	// Set stores the response of the key.
+	Set(key string, response *CachedResponse)
+
+	// DeletePrefix deletes the responses whose key starts with the prefix and
+	// returns how many were deleted.
+	DeletePrefix(prefix string) int
+}
+
+// CacheOptions configures the Cache.
+type CacheOptions struct {
+	// TTL is the freshness of the responses without max-age or s-maxage in the
+	// Cache-Control header. Zero only caches the responses with max-age or s-maxage.
+	TTL time.Duration
+
+	// StaleWhileRevalidate is the period a stale response is served while it is
+	// revalidated in the background, for the responses without the
+	// stale-while-revalidate directive. Default zero.
+	StaleWhileRevalidate time.Duration
+
+	// IgnoreQuery removes the query string from the cache key.
+	IgnoreQuery bool
+
+	// VaryHeaders is the list of request headers included in the cache key. The
+	// responses with a Vary header naming others are not stored.
+	VaryHeaders []string
+
+	// JWT includes the subject of the verified token in the cache key, which
+	// enables the caching of private responses and of the responses to requests
+	// with credentials.
+	JWT auth.JWT
+
+	// SubjectClaim is the token claim that identifies the subject. Default "sub".
+	SubjectClaim string
+
+	// Store keeps the responses. Default NewLRUCacheStore(64 MB).
+	Store CacheStore
+}
+
+// Cache caches the responses of the GET requests, also served to the HEAD
+// requests, honouring the Cache-Control headers of the requests and of the
+// responses. Concurrent requests for a response not cached are coalesced into
+// one request to the handler.
+//
+// The responses to requests with the Authorization or Cookie header are only
+// cached per subject, with JWT, or when marked public or s-maxage (RFC 7234 3.2),
+// and such requests are not coalesced without a subject.
+//
+// The cache key starts with the path of the request, so the responses of a
+// resource are invalidated by its path:
+//
+//	cache := middleware.NewCache(middleware.CacheOptions{TTL: 5 * time.Second})
+//	h := cache.Middleware()(h)
+//	// ...
+//
+//	cache.Invalidate("/users/")
+type Cache struct {
+	opts CacheOptions
+
+	mu       sync.Mutex // guard inFlight
+	inFlight map[string]*cacheCall
+}
+
+// cacheCall is a request to the handler in progress, shared by the concurrent requests.
+type cacheCall struct {
+	done     chan struct{}
+	response *CachedResponse // nil if the response is not cacheable.
+}
+
+// NewCache creates a Cache configured by CacheOptions.
+func NewCache(opts CacheOptions) *Cache {
+	if opts.SubjectClaim == "" {
+		opts.SubjectClaim = "sub"
+	}
+	if opts.Store == nil {
+		opts.Store = NewLRUCacheStore(64 << 20)
+	}
+	varyHeaders := make([]string, 0, len(opts.VaryHeaders))
+	for _, header := range opts.VaryHeaders {
+		varyHeaders = append(varyHeaders, http.CanonicalHeaderKey(header))
+	}
+	sort.Strings(varyHeaders)
+	opts.VaryHeaders = varyHeaders
+
+	return &Cache{
+		opts:     opts,
+		inFlight: make(map[string]*cacheCall),
+	}
+}
+
+// Invalidate deletes the responses whose cache key starts with the prefix.
+func (c *Cache) Invalidate(prefix string) int {
+	return c.opts.Store.DeletePrefix(prefix)
+}
+
+// Middleware returns the middleware that serves the responses from the cache.
+func (c *Cache) Middleware() Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			directives := parseCacheControl(r.Header.Get("Cache-Control"))
+			if _, ok := directives["no-store"]; ok {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			key, subject := c.key(r)
+			// requests with credentials of an unknown subject only share the
+			// responses marked for it.
+			restricted := !subject && (r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "")
+			now := time.Now()
+
+			_, noCache := directives["no-cache"]
+			if maxAge, ok := directives["max-age"]; ok && maxAge == "0" {
+				noCache = true
+			}
+
+			if cached, ok := c.opts.Store.Get(key); ok && !noCache && (cached.Shared || !restricted) {
+				age := cached.age(now)
+				switch {
+				case age < cached.MaxAge:
+					c.write(w, cached, "HIT", now)
+					return
+				case age < cached.MaxAge+cached.StaleWhileRevalidate:
+					c.revalidate(h, r, key, restricted)
+					c.write(w, cached, "STALE", now)
+					return
+				}
+			}
+
+			if r.Method == http.MethodHead { // only the responses of GET are stored.
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			if restricted {
+				buf := newResponseBuffer()
+				h.ServeHTTP(buf, r)
+				c.store(key, buf, true)
+				buf.header.Set("X-Cache", "MISS")
+				buf.writeTo(w)
+				return
+			}
+
+			call, leader := c.join(key)
+			if !leader {
+				<-call.done
+				if call.response != nil {
+					c.write(w, call.response, "HIT", time.Now())
+					return
+				}
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			buf := newResponseBuffer()
+			defer func() { c.leave(key, call) }()
+			h.ServeHTTP(buf, r)
+
+			call.response = c.store(key, buf, false)
+			buf.header.Set("X-Cache", "MISS")
+			buf.writeTo(w)
+		})
+	}
+}
+
+// join returns the call in progress for the key, or starts a new call when there
+// is none, in which case the caller is the leader.
+func (c *Cache) join(key string) (*cacheCall, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if call, ok := c.inFlight[key]; ok {
+		return call, false
+	}
+
+	call := &cacheCall{done: make(chan struct{})}
+	c.inFlight[key] = call
+	return call, true
+}
+
+func (c *Cache) leave(key string, call *cacheCall) {
+	c.mu.Lock()
+	delete(c.inFlight, key)
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// revalidate requests the response in the background, unless a request for the
+// key is already in progress.
+func (c *Cache) revalidate(h http.Handler, r *http.Request, key string, restricted bool) {
+	call, leader := c.join(key)
+	if !leader {
+		return
+	}
+
+	r = r.Clone(detachedContext{r.Context()})
+	r.Method = http.MethodGet
+	go func() {
+		defer c.leave(key, call)
+
+		buf := newResponseBuffer()
+		h.ServeHTTP(buf, r)
+		call.response = c.store(key, buf, restricted)
+	}()
+}
+
+// store stores the response if it is cacheable. The responses to restricted
+// requests, with credentials of an unknown subject, are only stored when shared.
+func (c *Cache) store(key string, buf *responseBuffer, restricted bool) *CachedResponse {
+	if buf.status != http.StatusOK || buf.header.Get("Set-Cookie") != "" || !c.varyCovered(buf.header) {
+		return nil
+	}
+
+	directives := parseCacheControl(buf.header.Get("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return nil
+	}
+	if _, ok := directives["no-cache"]; ok {
+		return nil
+	}
+	if _, ok := directives["private"]; ok && c.opts.JWT == nil {
+		return nil
+	}
+	_, public := directives["public"]
+	_, sMaxAge := directives["s-maxage"]
+	shared := public || sMaxAge
+	if restricted && !shared {
+		return nil
+	}
+
+	maxAge := c.opts.TTL
+	if v, ok := directives["s-maxage"]; ok {
+		maxAge = parseSeconds(v)
+	} else if v, ok := directives["max-age"]; ok {
+		maxAge = parseSeconds(v)
+	}
+	if maxAge <= 0 {
+		return nil
+	}
+
+	swr := c.opts.StaleWhileRevalidate
+	if v, ok := directives["stale-while-revalidate"]; ok {
+		swr = parseSeconds(v)
+	}
+
+	response := &CachedResponse{
+		Status:               buf.status,
+		Header:               buf.header.Clone(),
+		Body:                 append([]byte(nil), buf.body.Bytes()...),
+		StoredAt:             time.Now(),
+		MaxAge:               maxAge,
+		StaleWhileRevalidate: swr,
+		Shared:               shared,
+	}
+	c.opts.Store.Set(key, response)
+	return response
+}
+
+// varyCovered reports whether the request headers named by the Vary header of
+// the response are in the cache key. "Vary: *" is never covered.
+func (c *Cache) varyCovered(header http.Header) bool {
+	for _, v := range header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			i := sort.SearchStrings(c.opts.VaryHeaders, name)
+			if i == len(c.opts.VaryHeaders) || c.opts.VaryHeaders[i] != name {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (c *Cache) write(w http.ResponseWriter, cached *CachedResponse, status string, now time.Time) {
+	header := w.Header()
+	for k, v := range cached.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set("Age", strconv.Itoa(int(cached.age(now).Seconds())))
+	header.Set("X-Cache", status)
+	w.WriteHeader(cached.Status)
+	w.Write(cached.Body)
+}
+
+// key returns the cache key: the path, followed by the query, the vary headers and
+// the subject of the verified token, if any, in which case it returns true.
+func (c *Cache) key(r *http.Request) (string, bool) {
+	var sb strings.Builder
+	sb.WriteString(r.URL.Path)
+
+	if !c.opts.IgnoreQuery && r.URL.RawQuery != "" {
+		sb.WriteString("?")
+		sb.WriteString(r.URL.Query().Encode()) // sorted by key
+	}
+	for _, header := range c.opts.VaryHeaders {
+		fmt.Fprintf(&sb, "|%s=%s", header, r.Header.Get(header))
+	}
+	if c.opts.JWT != nil {
+		if sub, err := c.opts.JWT.GetDataToken(r, c.opts.SubjectClaim); err == nil && sub != nil {
+			fmt.Fprintf(&sb, "|sub=%v", sub)
+			return sb.String(), true
+		}
+	}
+
+	return sb.String(), false
+}
+
+// parseCacheControl parses the directives of a Cache-Control header.
+func parseCacheControl(header string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name != "" {
+			directives[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return directives
+}
+
+func parseSeconds(v string) time.Duration {
+	s, err := strconv.Atoi(v)
+	if err != nil || s < 0 {
+		return 0
+	}
+	return time.Duration(s) * time.Second
+}
+
+// detachedContext keeps the values of the parent context without its cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (d detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (d detachedContext) Done() <-chan struct{}             { return nil }
+func (d detachedContext) Err() error                        { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
+
+// lruCacheStore is a CacheStore limited in size that evicts the least recently
+// used responses.
+type lruCacheStore struct {
+	maxBytes int64
+	size     int64
+	ll       *list.List
+	items    map[string]*list.Element
+	mu       sync.Mutex // guard size, ll and items
+}
+
+type lruItem struct {
+	key      string
+	response *CachedResponse
+	size     int64
+}
+
+// NewLRUCacheStore creates a CacheStore that keeps up to maxBytes of responses in
+// memory, evicting the least recently used.
+func NewLRUCacheStore(maxBytes int64) CacheStore {
+	return &lruCacheStore{
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get implements interface CacheStore.
+func (l *lruCacheStore) Get(key string) (*CachedResponse, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	l.ll.MoveToFront(e)
+	return e.Value.(*lruItem).response, true
+}
+
+// Set implements interface CacheStore.
+func (l *lruCacheStore) Set(key string, response *CachedResponse) {
+	size := response.size() + int64(len(key))
+	if size > l.maxBytes {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.items[key]; ok {
+		l.remove(e)
+	}
+	l.items[key] = l.ll.PushFront(&lruItem{key: key, response: response, size: size})
+	l.size += size
+
+	for l.size > l.maxBytes {
+		l.remove(l.ll.Back())
+	}
+}
+
+// DeletePrefix implements interface CacheStore.
+func (l *lruCacheStore) DeletePrefix(prefix string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	deleted := 0
+	for key, e := range l.items {
+		if strings.HasPrefix(key, prefix) {
+			l.remove(e)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+func (l *lruCacheStore) remove(e *list.Element) {
+	item := l.ll.Remove(e).(*lruItem)
+	delete(l.items, item.key)
+	l.size -= item.size
+}
@@ -0,0 +1,314 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_HitAndMiss(t *testing.T) {
+	var calls int32
+	cache := NewCache(CacheOptions{TTL: time.Minute})
+	h := cache.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Write([]byte(r.URL.RawQuery + strconv.Itoa(int(n))))
+	}))
+
+	tests := []struct {
+		target string
+		cache  string
+		body   string
+	}{
+		{"/users?a=1&b=2", "MISS", "a=1&b=21"},
+		{"/users?b=2&a=1", "HIT", "a=1&b=21"},
+		{"/users?a=2", "MISS", "a=22"},
+		{"/users?a=2", "HIT", "a=22"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+		if got := rec.Header().Get("X-Cache"); got != tt.cache {
+			t.Errorf("%s: X-Cache = %q, want %q", tt.target, got, tt.cache)
+		}
+		if rec.Body.String() != tt.body {
+			t.Errorf("%s: body = %q, want %q", tt.target, rec.Body.String(), tt.body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/users?a=2", nil))
+	if rec.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("HEAD: X-Cache = %q, want HIT", rec.Header().Get("X-Cache"))
+	}
+
+	if n := cache.Invalidate("/users"); n != 2 {
+		t.Errorf("Invalidate() = %d, want 2", n)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users?a=2", nil))
+	if rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("after Invalidate: X-Cache = %q, want MISS", rec.Header().Get("X-Cache"))
+	}
+}
+
+func TestCache_CacheControl(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		reqControl   string
+		status       int
+		cached       bool
+	}{
+		{"default TTL", "", "", http.StatusOK, true},
+		{"max-age", "max-age=60", "", http.StatusOK, true},
+		{"no-store", "no-store", "", http.StatusOK, false},
+		{"no-cache", "no-cache", "", http.StatusOK, false},
+		{"private", "private, max-age=60", "", http.StatusOK, false},
+		{"max-age zero", "max-age=0", "", http.StatusOK, false},
+		{"s-maxage", "max-age=0, s-maxage=60", "", http.StatusOK, true},
+		{"error", "max-age=60", "", http.StatusInternalServerError, false},
+		{"request no-cache", "max-age=60", "no-cache", http.StatusOK, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			h := NewCache(CacheOptions{TTL: time.Minute}).Middleware()(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					atomic.AddInt32(&calls, 1)
+					if tt.cacheControl != "" {
+						w.Header().Set("Cache-Control", tt.cacheControl)
+					}
+					w.WriteHeader(tt.status)
+				}))
+
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Cache-Control", tt.reqControl)
+				h.ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			want := int32(2)
+			if tt.cached {
+				want = 1
+			}
+			if calls != want {
+				t.Errorf("handler calls = %d, want %d", calls, want)
+			}
+		})
+	}
+}
+
+func TestCache_VaryHeaders(t *testing.T) {
+	h := NewCache(CacheOptions{TTL: time.Minute, VaryHeaders: []string{"accept-language"}}).Middleware()(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Header.Get("Accept-Language")))
+		}))
+
+	for _, lang := range []string{"en", "pt-BR", "en"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", lang)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Body.String() != lang {
+			t.Errorf("Accept-Language %s: body = %q", lang, rec.Body.String())
+		}
+	}
+}
+
+func TestCache_Credentials(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		shared       bool
+	}{
+		{"default TTL", "", false},
+		{"max-age", "max-age=60", false},
+		{"public", "public, max-age=60", true},
+		{"s-maxage", "s-maxage=60", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewCache(CacheOptions{TTL: 5 * time.Second}).Middleware()(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					if tt.cacheControl != "" {
+						w.Header().Set("Cache-Control", tt.cacheControl)
+					}
+					w.Write([]byte(r.Header.Get("Authorization")))
+				}))
+
+			for _, token := range []string{"Bearer alice", "Bearer bob"} {
+				req := httptest.NewRequest(http.MethodGet, "/me", nil)
+				req.Header.Set("Authorization", token)
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+
+				if !tt.shared && rec.Body.String() != token {
+					t.Errorf("%s: body = %q, X-Cache = %q", token, rec.Body.String(), rec.Header().Get("X-Cache"))
+				}
+				if tt.shared && rec.Body.String() != "Bearer alice" {
+					t.Errorf("%s: body = %q, want the shared response", token, rec.Body.String())
+				}
+			}
+
+			// a request with credentials does not store a response for the anonymous requests.
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/me", nil))
+			if !tt.shared && rec.Body.String() != "" {
+				t.Errorf("anonymous: body = %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCache_Vary(t *testing.T) {
+	tests := []struct {
+		name        string
+		vary        string
+		varyHeaders []string
+		cached      bool
+	}{
+		{"not covered", "Accept-Encoding", nil, false},
+		{"covered", "accept-encoding", []string{"Accept-Encoding"}, true},
+		{"partially covered", "Accept-Encoding, Accept-Language", []string{"Accept-Encoding"}, false},
+		{"star", "*", []string{"Accept-Encoding"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			h := NewCache(CacheOptions{TTL: time.Minute, VaryHeaders: tt.varyHeaders}).Middleware()(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					atomic.AddInt32(&calls, 1)
+					w.Header().Set("Vary", tt.vary)
+					w.Header().Set("Content-Encoding", r.Header.Get("Accept-Encoding"))
+				}))
+
+			for _, encoding := range []string{"gzip", "", ""} {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Accept-Encoding", encoding)
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+
+				if rec.Header().Get("Content-Encoding") != encoding {
+					t.Errorf("Accept-Encoding %q: Content-Encoding = %q", encoding,
+						rec.Header().Get("Content-Encoding"))
+				}
+			}
+
+			want := int32(3)
+			if tt.cached {
+				want = 2
+			}
+			if calls != want {
+				t.Errorf("handler calls = %d, want %d", calls, want)
+			}
+		})
+	}
+}
+
+func TestCache_StaleWhileRevalidate(t *testing.T) {
+	var calls int32
+	refreshed := make(chan struct{}, 1)
+	store := NewLRUCacheStore(1 << 20)
+	h := NewCache(CacheOptions{Store: store}).Middleware()(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&calls, 1)
+			w.Header().Set("Cache-Control", "max-age=1, stale-while-revalidate=60")
+			w.Write([]byte(strconv.Itoa(int(n))))
+			if n > 1 {
+				refreshed <- struct{}{}
+			}
+		}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	cached, _ := store.Get("/")
+	cached.StoredAt = cached.StoredAt.Add(-2 * time.Second)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Header().Get("X-Cache") != "STALE" || rec.Body.String() != "1" {
+		t.Fatalf("X-Cache = %q, body = %q, want STALE and 1", rec.Header().Get("X-Cache"), rec.Body.String())
+	}
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("the response was not revalidated")
+	}
+
+	time.Sleep(10 * time.Millisecond) // wait for the store.
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Header().Get("X-Cache") != "HIT" || rec.Body.String() != "2" {
+		t.Errorf("X-Cache = %q, body = %q, want HIT and 2", rec.Header().Get("X-Cache"), rec.Body.String())
+	}
+}
+
+func TestCache_Coalescing(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	h := NewCache(CacheOptions{TTL: time.Minute}).Middleware()(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			w.Write([]byte("coalesced"))
+		}))
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 10)
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			bodies[i] = rec.Body.String()
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("handler calls = %d, want 1", calls)
+	}
+	for i, body := range bodies {
+		if body != "coalesced" {
+			t.Errorf("request %d: body = %q", i, body)
+		}
+	}
+}
+
+func TestLRUCacheStore_Evict(t *testing.T) {
+	store := NewLRUCacheStore(25)
+	response := func() *CachedResponse {
+		return &CachedResponse{Body: []byte("0123456789")}
+	}
+
+	store.Set("a", response())
+	store.Set("b", response())
+	store.Get("a")
+	store.Set("c", response()) // evicts b, the least recently used.
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := store.Get(key); ok != want {
+			t.Errorf("Get(%q) = %v, want %v", key, ok, want)
+		}
+	}
+
+	store.Set("big", &CachedResponse{Body: make([]byte, 100)})
+	if _, ok := store.Get("big"); ok {
+		t.Error("response larger than the store was stored")
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// Middleware wraps a http.Handler, as CORS and GZIP.
+type Middleware func(http.Handler) http.Handler
+
+// NextFunc is a middleware that receives the next handler of the flow, as
+// Auth.RequireTokenAuth.
+type NextFunc func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc)
+
+// FromNextFunc converts a NextFunc into a Middleware.
+func FromNextFunc(fn NextFunc) Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fn(w, r, h.ServeHTTP)
+		})
+	}
+}
+
+// ToNextFunc converts a Middleware into a NextFunc.
+func ToNextFunc(m Middleware) NextFunc {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		m(next).ServeHTTP(w, r)
+	}
+}
+
+// Chain composes middlewares. The first middleware added is the outermost,
+// so it is the first to receive the request.
+type Chain struct {
+	middlewares []Middleware
+}
+
+// NewChain creates a Chain with the given middlewares.
+func NewChain(middlewares ...Middleware) *Chain {
+	return &Chain{middlewares: append([]Middleware(nil), middlewares...)}
+}
+
+// Use adds middlewares to the end of the chain.
+func (c *Chain) Use(middlewares ...Middleware) *Chain {
+	c.middlewares = append(c.middlewares, middlewares...)
+	return c
+}
+
+// UseFunc adds middlewares with the NextFunc signature to the end of the chain.
+func (c *Chain) UseFunc(fns ...NextFunc) *Chain {
+	for _, fn := range fns {
+		c.middlewares = append(c.middlewares, FromNextFunc(fn))
+	}
+	return c
+}
+
+// Append returns a new chain with the middlewares of this chain followed by the
+// given middlewares, leaving this chain unchanged. It is used to build per-route
+// chains from a common chain.
+func (c *Chain) Append(middlewares ...Middleware) *Chain {
+	chain := make([]Middleware, 0, len(c.middlewares)+len(middlewares))
+	chain = append(chain, c.middlewares...)
+	chain = append(chain, middlewares...)
+	return &Chain{middlewares: chain}
+}
+
+// Then wraps the handler with the middlewares of the chain and returns the
+// resulting handler. A nil handler is replaced by http.DefaultServeMux.
+func (c *Chain) Then(h http.Handler) http.Handler {
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		h = c.middlewares[i](h)
+	}
+	return h
+}
+
+// ThenFunc works like Then but receives a http.HandlerFunc.
+func (c *Chain) ThenFunc(fn http.HandlerFunc) http.Handler {
+	if fn == nil {
+		return c.Then(nil)
+	}
+	return c.Then(fn)
+}
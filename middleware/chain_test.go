@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func tagMiddleware(tag string) Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(tag))
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func tagNextFunc(tag string) NextFunc {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		w.Write([]byte(tag))
+		next(w, r)
+	}
+}
+
+func TestChain_Then(t *testing.T) {
+	base := NewChain(tagMiddleware("a")).UseFunc(tagNextFunc("b"))
+	route := base.Append(tagMiddleware("c"), FromNextFunc(ToNextFunc(tagMiddleware("d"))))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("h"))
+	})
+
+	tests := []struct {
+		chain *Chain
+		want  string
+	}{
+		{base, "abh"},
+		{route, "abcdh"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		tt.chain.ThenFunc(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("body = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestChain_Compose(t *testing.T) {
+	h := NewChain(CORS, GZIP).ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.Repeat("chain", 500)))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Error("CORS not applied")
+	}
+	if rec.Header().Get("Content-Encoding") != EncodingGzip {
+		t.Error("GZIP not applied")
+	}
+}
@@ -0,0 +1,388 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"github.com/tsmweb/go-helper-api/httputil"
+)
+
+// Content codings supported by the compression middlewares.
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+	EncodingBrotli  = "br"
+	EncodingZstd    = "zstd"
+)
+
+// CompressionLevel represents the compression level, mapped to the equivalent
+// level of each encoding.
+type CompressionLevel int
+
+const (
+	// CompressionDefault represents the default level of each encoding.
+	CompressionDefault CompressionLevel = iota
+
+	// CompressionFastest represents the fastest level of each encoding.
+	CompressionFastest
+
+	// CompressionBest represents the level with the best compression of each encoding.
+	CompressionBest
+)
+
+// CompressOptions configures the Compress middleware.
+type CompressOptions struct {
+	// Encodings is the list of encodings in order of preference, used when the
+	// client accepts them with the same q-value. Default zstd, br, gzip and deflate.
+	Encodings []string
+
+	// Level is the compression level. Default CompressionDefault.
+	Level CompressionLevel
+
+	// MinSize is the minimum size in bytes of the response to be compressed.
+	// Default 1024, a negative value compresses any size.
+	MinSize int
+
+	// ContentTypes is the list of content types compressed, if empty all the
+	// content types not excluded are compressed. A content type ending with
+	// "/*" matches all its subtypes, as in "text/*".
+	ContentTypes []string
+
+	// ExcludedContentTypes is the list of content types never compressed.
+	// Default DefaultExcludedContentTypes.
+	ExcludedContentTypes []string
+}
+
+// DefaultExcludedContentTypes are content types that are already compressed.
+var DefaultExcludedContentTypes = []string{
+	httputil.MimeTypeText(httputil.MimeImageJPEG),
+	httputil.MimeTypeText(httputil.MimeImagePNG),
+	httputil.MimeTypeText(httputil.MimeAudioMP3),
+	httputil.MimeTypeText(httputil.MimeVideoMP4),
+	httputil.MimeTypeText(httputil.MimeApplicationPDF),
+	"image/gif",
+	"image/webp",
+	"video/*",
+	"audio/*",
+	"font/woff2",
+	"application/zip",
+	"application/gzip",
+	"application/zstd",
+}
+
+// NewCompress creates a middleware that compresses the http responses with the
+// encoding negotiated through the Accept-Encoding header.
+func NewCompress(opts CompressOptions) Middleware {
+	c := newCompressor(opts)
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := c.negotiate(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, c: c, encoding: encoding, status: http.StatusOK}
+			defer cw.close()
+
+			h.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// encoder is implemented by the writers of all encodings.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+type compressor struct {
+	encodings []string
+	minSize   int
+	types     []string
+	excluded  []string
+	pools     map[string]*sync.Pool
+}
+
+func newCompressor(opts CompressOptions) *compressor {
+	if len(opts.Encodings) == 0 {
+		opts.Encodings = []string{EncodingZstd, EncodingBrotli, EncodingGzip, EncodingDeflate}
+	}
+	if opts.MinSize == 0 {
+		opts.MinSize = 1024
+	}
+	if opts.ExcludedContentTypes == nil {
+		opts.ExcludedContentTypes = DefaultExcludedContentTypes
+	}
+
+	c := &compressor{
+		minSize:  opts.MinSize,
+		types:    opts.ContentTypes,
+		excluded: opts.ExcludedContentTypes,
+		pools:    make(map[string]*sync.Pool),
+	}
+
+	for _, encoding := range opts.Encodings {
+		encoding = strings.ToLower(encoding)
+		newEncoder := encoderFactory(encoding, opts.Level)
+		if newEncoder == nil {
+			continue
+		}
+		c.encodings = append(c.encodings, encoding)
+		c.pools[encoding] = &sync.Pool{New: func() interface{} { return newEncoder() }}
+	}
+
+	return c
+}
+
+// encoderFactory returns the function that creates the encoder of the given
+// encoding, or nil if the encoding is not supported.
+func encoderFactory(encoding string, level CompressionLevel) func() encoder {
+	switch encoding {
+	case EncodingGzip:
+		l := map[CompressionLevel]int{CompressionFastest: gzip.BestSpeed, CompressionBest: gzip.BestCompression}
+		return func() encoder {
+			w, _ := gzip.NewWriterLevel(io.Discard, levelOr(l, level, gzip.DefaultCompression))
+			return w
+		}
+	case EncodingDeflate: // "deflate" is the zlib format (RFC 1950)
+		l := map[CompressionLevel]int{CompressionFastest: zlib.BestSpeed, CompressionBest: zlib.BestCompression}
+		return func() encoder {
+			w, _ := zlib.NewWriterLevel(io.Discard, levelOr(l, level, zlib.DefaultCompression))
+			return w
+		}
+	case EncodingBrotli:
+		l := map[CompressionLevel]int{CompressionFastest: brotli.BestSpeed, CompressionBest: brotli.BestCompression}
+		return func() encoder {
+			return brotli.NewWriterLevel(io.Discard, levelOr(l, level, 4))
+		}
+	case EncodingZstd:
+		l := map[CompressionLevel]int{
+			CompressionFastest: int(zstd.SpeedFastest),
+			CompressionBest:    int(zstd.SpeedBestCompression),
+		}
+		return func() encoder {
+			w, _ := zstd.NewWriter(io.Discard,
+				zstd.WithEncoderLevel(zstd.EncoderLevel(levelOr(l, level, int(zstd.SpeedDefault)))),
+				zstd.WithEncoderConcurrency(1))
+			return w
+		}
+	}
+	return nil
+}
+
+func levelOr(levels map[CompressionLevel]int, level CompressionLevel, def int) int {
+	if l, ok := levels[level]; ok {
+		return l
+	}
+	return def
+}
+
+// negotiate returns the encoding with the highest q-value in the Accept-Encoding
+// header, breaking ties by the order of preference. It returns an empty string
+// when none of the encodings is accepted.
+func (c *compressor) negotiate(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	accepted := parseQValues(acceptEncoding)
+	best, bestQ := "", 0.0
+
+	for _, encoding := range c.encodings {
+		q, ok := accepted[encoding]
+		if !ok {
+			q = accepted["*"]
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+
+	return best
+}
+
+// parseQValues parses a header with q-values such as "gzip;q=0.8, br".
+func parseQValues(header string) map[string]float64 {
+	values := make(map[string]float64)
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		params = strings.TrimSpace(params)
+		if strings.HasPrefix(params, "q=") {
+			v, err := strconv.ParseFloat(params[2:], 64)
+			if err != nil {
+				continue
+			}
+			q = v
+		}
+
+		values[name] = q
+	}
+
+	return values
+}
+
+// compressible reports whether the content type can be compressed.
+func (c *compressor) compressible(contentType string) bool {
+	contentType, _, _ = strings.Cut(contentType, ";")
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+
+	if matchContentType(c.excluded, contentType) {
+		return false
+	}
+	return len(c.types) == 0 || matchContentType(c.types, contentType)
+}
+
+func matchContentType(types []string, contentType string) bool {
+	for _, t := range types {
+		if strings.HasSuffix(t, "/*") {
+			if strings.HasPrefix(contentType, t[:len(t)-1]) {
+				return true
+			}
+		} else if t == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter buffers the beginning of the response to decide whether it is
+// compressed, based on its status, content type and size.
+type compressWriter struct {
+	http.ResponseWriter
+	c        *compressor
+	encoding string
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	enc         encoder
+}
+
+// WriteHeader implements interface http.ResponseWriter.
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader || status < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+	cw.wroteHeader = true
+
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		cw.passthrough()
+	}
+}
+
+// Write implements interface http.ResponseWriter.
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	cw.wroteHeader = true
+
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.c.minSize {
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush implements interface http.Flusher, compressing the response being streamed
+// regardless of its size.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide()
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the original http.ResponseWriter.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide starts the compression if the response is eligible, otherwise writes
+// the buffered response as is.
+func (cw *compressWriter) decide() error {
+	header := cw.Header()
+
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if header.Get("Content-Encoding") != "" || !cw.c.compressible(header.Get("Content-Type")) {
+		return cw.passthrough()
+	}
+
+	header.Del("Content-Length")
+	header.Set("Content-Encoding", cw.encoding)
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	cw.decided = true
+	cw.enc = cw.c.pools[cw.encoding].Get().(encoder)
+	cw.enc.Reset(cw.ResponseWriter)
+
+	return cw.writeBuffer(cw.enc)
+}
+
+func (cw *compressWriter) passthrough() error {
+	cw.decided = true
+	cw.ResponseWriter.WriteHeader(cw.status)
+	return cw.writeBuffer(cw.ResponseWriter)
+}
+
+func (cw *compressWriter) writeBuffer(w io.Writer) error {
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(cw.buf)
+	cw.buf = nil
+	return err
+}
+
+// close writes the responses smaller than the minimum size and returns the
+// encoder to the pool.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.wroteHeader {
+			cw.passthrough()
+		}
+		return
+	}
+
+	if cw.enc != nil {
+		cw.enc.Close()
+		cw.enc.Reset(io.Discard)
+		cw.c.pools[cw.encoding].Put(cw.enc)
+		cw.enc = nil
+	}
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompress_negotiate(t *testing.T) {
+	c := newCompressor(CompressOptions{})
+
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", EncodingGzip},
+		{"gzip, deflate, br, zstd", EncodingZstd},
+		{"gzip;q=1.0, br;q=0.5", EncodingGzip},
+		{"br;q=0.9, gzip;q=0.9", EncodingBrotli},
+		{"*", EncodingZstd},
+		{"*;q=0.5, zstd;q=0", EncodingBrotli},
+		{"gzip;q=0", ""},
+	}
+
+	for _, tt := range tests {
+		if got := c.negotiate(tt.acceptEncoding); got != tt.want {
+			t.Errorf("negotiate(%q) = %q, want %q", tt.acceptEncoding, got, tt.want)
+		}
+	}
+}
+
+func TestCompress_Encodings(t *testing.T) {
+	body := strings.Repeat(`{"name":"compress"}`, 200)
+	h := NewCompress(CompressOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	}))
+
+	decoders := map[string]func(r io.Reader) io.Reader{
+		EncodingGzip: func(r io.Reader) io.Reader {
+			zr, _ := gzip.NewReader(r)
+			return zr
+		},
+		EncodingDeflate: func(r io.Reader) io.Reader {
+			zr, _ := zlib.NewReader(r)
+			return zr
+		},
+		EncodingBrotli: func(r io.Reader) io.Reader { return brotli.NewReader(r) },
+		EncodingZstd: func(r io.Reader) io.Reader {
+			zr, _ := zstd.NewReader(r)
+			return zr
+		},
+	}
+
+	for encoding, decode := range decoders {
+		t.Run(encoding, func(t *testing.T) {
+			for i := 0; i < 2; i++ { // the second request reuses the pooled encoder
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Accept-Encoding", encoding)
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+
+				if got := rec.Header().Get("Content-Encoding"); got != encoding {
+					t.Fatalf("Content-Encoding = %q, want %q", got, encoding)
+				}
+				if rec.Body.Len() >= len(body) {
+					t.Errorf("body not compressed: %d bytes", rec.Body.Len())
+				}
+
+				b, err := io.ReadAll(decode(bytes.NewReader(rec.Body.Bytes())))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(b) != body {
+					t.Errorf("decoded body does not match")
+				}
+			}
+		})
+	}
+}
+
+func TestCompress_Skip(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"below minimum size", "application/json", `{"small":true}`},
+		{"excluded content type", "image/png", strings.Repeat("x", 2048)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewCompress(CompressOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(http.StatusCreated)
+				io.WriteString(w, tt.body)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusCreated {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != "" {
+				t.Errorf("Content-Encoding = %q, want empty", got)
+			}
+			if rec.Body.String() != tt.body {
+				t.Errorf("body = %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCompress_Flush(t *testing.T) {
+	h := GZIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: 1\n\n")
+		w.(http.Flusher).Flush()
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if !rec.Flushed {
+		t.Error("response not flushed")
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != EncodingGzip {
+		t.Errorf("Content-Encoding = %q, want gzip", got)
+	}
+}
@@ -0,0 +1,10 @@
+package middleware
+
+// contextKey is the type of the keys stored by the middlewares in the request context.
+type contextKey int
+
+const (
+	basicAuthUserKey contextKey = iota
+	cspNonceKey
+	clientIPKey
+)
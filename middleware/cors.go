@@ -2,17 +2,240 @@ package middleware
 
 import (
 	"net/http"
-
-	"github.com/gorilla/handlers"
+	"strconv"
+	"strings"
 )
 
+// CORSOptions configures the CORS middleware.
+type CORSOptions struct {
+	// AllowedOrigins is the list of origins allowed to make cross-origin requests.
+	// An origin may be "*" to allow any origin, or contain a wildcard subdomain
+	// as in "https://*.example.com".
+	AllowedOrigins []string
+
+	// AllowOriginFunc validates the origins not matched by AllowedOrigins.
+	AllowOriginFunc func(origin string) bool
+
+	// AllowedMethods is the list of methods allowed. Default GET, POST and HEAD.
+	AllowedMethods []string
+
+	// AllowedHeaders is the list of request headers allowed besides Accept,
+	// Accept-Language, Content-Language and Origin. Default Content-Type.
+	AllowedHeaders []string
+
+	// ExposedHeaders is the list of response headers exposed to the client.
+	ExposedHeaders []string
+
+	// AllowCredentials allows the request to include cookies and HTTP authentication.
+	// It can not be combined with the origin "*", which would let any site read the
+	// responses with the credentials of the user; an AllowOriginFunc must also not
+	// accept any origin.
+	AllowCredentials bool
+
+	// MaxAge is the number of seconds the preflight response can be cached.
+	// Zero omits the header.
+	MaxAge int
+}
+
+// DefaultCORSOptions are the options used by CORS.
+var DefaultCORSOptions = CORSOptions{
+	AllowedOrigins: []string{"*"},
+	AllowedMethods: []string{"GET", "POST", "OPTIONS", "PUT", "DELETE"},
+	AllowedHeaders: []string{"Authorization", "Content-Type", "Location"},
+}
+
 // CORS sets the accepted headers, permitted sources and methods accepted by the request.
 func CORS(h http.Handler) http.Handler {
-	headersOK := handlers.AllowedHeaders([]string{"Authorization", "Content-Type", "Location"})
-	originsOK := handlers.AllowedOrigins([]string{"*"})
-	methodsOK := handlers.AllowedMethods([]string{"GET", "POST", "OPTIONS", "PUT", "DELETE"})
+	return NewCORS(DefaultCORSOptions)(h)
+}
+
+// NewCORS creates a CORS middleware configured by CORSOptions.
+//
+// NewCORS panics if AllowCredentials is combined with the origin "*".
+func NewCORS(opts CORSOptions) Middleware {
+	c := newCORS(opts)
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				c.preflight(w, r)
+				return
+			}
+
+			c.actual(w, r)
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+type cors struct {
+	allowAll        bool
+	origins         []string
+	wildcards       [][2]string // prefix and suffix of the wildcard origins
+	allowOriginFunc func(origin string) bool
+	methods         map[string]bool
+	methodsText     string
+	headers         map[string]bool
+	headersText     string
+	exposedText     string
+	credentials     bool
+	maxAge          string
+}
+
+func newCORS(opts CORSOptions) *cors {
+	c := &cors{
+		allowOriginFunc: opts.AllowOriginFunc,
+		methods:         make(map[string]bool),
+		headers:         make(map[string]bool),
+		credentials:     opts.AllowCredentials,
+	}
+
+	for _, origin := range opts.AllowedOrigins {
+		origin = strings.ToLower(origin)
+		switch {
+		case origin == "*":
+			if opts.AllowCredentials {
+				panic(`middleware: CORS origin "*" does not allow credentials`)
+			}
+			c.allowAll = true
+		case strings.Contains(origin, "*"):
+			i := strings.Index(origin, "*")
+			c.wildcards = append(c.wildcards, [2]string{origin[:i], origin[i+1:]})
+		default:
+			c.origins = append(c.origins, origin)
+		}
+	}
+
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodPost, http.MethodHead}
+	}
+	names := make([]string, 0, len(methods))
+	for _, method := range methods {
+		method = strings.ToUpper(method)
+		c.methods[method] = true
+		names = append(names, method)
+	}
+	c.methodsText = strings.Join(names, ", ")
+
+	headers := opts.AllowedHeaders
+	if len(headers) == 0 {
+		headers = []string{"Content-Type"}
+	}
+	names = make([]string, 0, len(headers))
+	for _, header := range headers {
+		header = http.CanonicalHeaderKey(header)
+		c.headers[header] = true
+		names = append(names, header)
+	}
+	c.headersText = strings.Join(names, ", ")
+
+	// headers that browsers always consider safe.
+	for _, header := range []string{"Accept", "Accept-Language", "Content-Language", "Origin"} {
+		c.headers[header] = true
+	}
+
+	c.exposedText = strings.Join(opts.ExposedHeaders, ", ")
+
+	if opts.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(opts.MaxAge)
+	}
+
+	return c
+}
+
+// preflight answers the OPTIONS request sent by the browser before the actual request.
+func (c *cors) preflight(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	origin := r.Header.Get("Origin")
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+
+	if !c.originAllowed(origin) || !c.methods[method] || !c.headersAllowed(r) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	c.allowOrigin(header, origin)
+	header.Set("Access-Control-Allow-Methods", c.methodsText)
+	header.Set("Access-Control-Allow-Headers", c.headersText)
+	if c.credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if c.maxAge != "" {
+		header.Set("Access-Control-Max-Age", c.maxAge)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// actual sets the CORS headers of the actual request.
+func (c *cors) actual(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	if !c.allowAll || c.credentials || c.allowOriginFunc != nil {
+		header.Add("Vary", "Origin")
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" || !c.originAllowed(origin) || !c.methods[r.Method] {
+		return
+	}
+
+	c.allowOrigin(header, origin)
+	if c.credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if c.exposedText != "" {
+		header.Set("Access-Control-Expose-Headers", c.exposedText)
+	}
+}
+
+// allowOrigin sets Access-Control-Allow-Origin. The origin is echoed instead of "*"
+// when credentials are allowed, as browsers reject the wildcard in that case.
+func (c *cors) allowOrigin(header http.Header, origin string) {
+	if c.allowAll && !c.credentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	header.Set("Access-Control-Allow-Origin", origin)
+}
+
+func (c *cors) originAllowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if c.allowAll {
+		return true
+	}
+
+	o := strings.ToLower(origin)
+	for _, allowed := range c.origins {
+		if o == allowed {
+			return true
+		}
+	}
+	for _, w := range c.wildcards {
+		if len(o) > len(w[0])+len(w[1]) && strings.HasPrefix(o, w[0]) && strings.HasSuffix(o, w[1]) {
+			return true
+		}
+	}
+
+	return c.allowOriginFunc != nil && c.allowOriginFunc(origin)
+}
 
-	handler := handlers.CORS(originsOK, headersOK, methodsOK)(h)
+func (c *cors) headersAllowed(r *http.Request) bool {
+	requested := r.Header.Get("Access-Control-Request-Headers")
+	if requested == "" {
+		return true
+	}
 
-	return handler
+	for _, header := range strings.Split(requested, ",") {
+		header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+		if header != "" && !c.headers[header] {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCORS_Preflight(t *testing.T) {
+	h := NewCORS(CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{"GET", "PATCH"},
+		AllowedHeaders:   []string{"Authorization", "X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("preflight reached the handler")
+	}))
+
+	tests := []struct {
+		name    string
+		origin  string
+		method  string
+		headers string
+		allowed bool
+	}{
+		{"exact origin", "https://app.example.com", "PATCH", "authorization, x-request-id", true},
+		{"wildcard origin", "https://api.example.org", "GET", "", true},
+		{"wildcard without subdomain", "https://.example.org", "GET", "", false},
+		{"unknown origin", "https://evil.com", "GET", "", false},
+		{"method not allowed", "https://app.example.com", "DELETE", "", false},
+		{"header not allowed", "https://app.example.com", "GET", "X-Custom", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			allowOrigin := rec.Header().Get("Access-Control-Allow-Origin")
+			if tt.allowed != (allowOrigin == tt.origin) {
+				t.Fatalf("Access-Control-Allow-Origin = %q", allowOrigin)
+			}
+			if tt.allowed {
+				if rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+					t.Error("Access-Control-Allow-Credentials not set")
+				}
+				if rec.Header().Get("Access-Control-Max-Age") != "600" {
+					t.Errorf("Access-Control-Max-Age = %q", rec.Header().Get("Access-Control-Max-Age"))
+				}
+			}
+			if vary := strings.Join(rec.Header().Values("Vary"), ","); !strings.Contains(vary, "Origin") {
+				t.Errorf("Vary = %q", vary)
+			}
+		})
+	}
+}
+
+func TestCORS_Actual(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+	CORS(handler).ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("default Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "" {
+		t.Errorf("default Vary = %q, want empty", got)
+	}
+
+	h := NewCORS(CORSOptions{
+		AllowOriginFunc: func(origin string) bool { return strings.HasSuffix(origin, ".local") },
+		ExposedHeaders:  []string{"X-Request-ID"},
+	})(handler)
+	req.Header.Set("Origin", "http://dev.local")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "http://dev.local" {
+		t.Errorf("Access-Control-Allow-Origin = %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-ID" {
+		t.Errorf("Access-Control-Expose-Headers = %q", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("Vary = %q, want Origin", got)
+	}
+}
+
+func TestCORS_AllowAllWithCredentials(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error(`NewCORS() did not panic with origin "*" and credentials`)
+		}
+	}()
+	NewCORS(CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+}
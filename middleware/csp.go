@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+)
+
+// CSPDirective represents a Content-Security-Policy directive ("default-src",
+// "script-src", ...).
+type CSPDirective string
+
+// Content-Security-Policy directives.
+const (
+	CSPDefaultSrc              CSPDirective = "default-src"
+	CSPScriptSrc               CSPDirective = "script-src"
+	CSPStyleSrc                CSPDirective = "style-src"
+	CSPImgSrc                  CSPDirective = "img-src"
+	CSPConnectSrc              CSPDirective = "connect-src"
+	CSPFontSrc                 CSPDirective = "font-src"
+	CSPObjectSrc               CSPDirective = "object-src"
+	CSPMediaSrc                CSPDirective = "media-src"
+	CSPFrameSrc                CSPDirective = "frame-src"
+	CSPWorkerSrc               CSPDirective = "worker-src"
+	CSPManifestSrc             CSPDirective = "manifest-src"
+	CSPFrameAncestors          CSPDirective = "frame-ancestors"
+	CSPBaseURI                 CSPDirective = "base-uri"
+	CSPFormAction              CSPDirective = "form-action"
+	CSPReportURI               CSPDirective = "report-uri"
+	CSPReportTo                CSPDirective = "report-to"
+	CSPUpgradeInsecureRequests CSPDirective = "upgrade-insecure-requests"
+)
+
+// Content-Security-Policy source expressions.
+const (
+	CSPSelf          = "'self'"
+	CSPNone          = "'none'"
+	CSPUnsafeInline  = "'unsafe-inline'"
+	CSPUnsafeEval    = "'unsafe-eval'"
+	CSPStrictDynamic = "'strict-dynamic'"
+	CSPData          = "data:"
+	CSPBlob          = "blob:"
+	CSPHTTPS         = "https:"
+)
+
+// CSP builds a Content-Security-Policy. The directives keep the order in which
+// they are added.
+//
+//	csp := middleware.NewCSP().
+//		Add(middleware.CSPDefaultSrc, middleware.CSPSelf).
+//		Add(middleware.CSPImgSrc, middleware.CSPSelf, middleware.CSPData).
+//		Nonce(middleware.CSPScriptSrc, middleware.CSPStyleSrc)
+type CSP struct {
+	directives []CSPDirective
+	sources    map[CSPDirective][]string
+	nonce      map[CSPDirective]bool
+}
+
+// NewCSP creates an empty CSP.
+func NewCSP() *CSP {
+	return &CSP{
+		sources: make(map[CSPDirective][]string),
+		nonce:   make(map[CSPDirective]bool),
+	}
+}
+
+// Add adds the sources to the directive.
+func (c *CSP) Add(directive CSPDirective, sources ...string) *CSP {
+	if _, ok := c.sources[directive]; !ok {
+		c.directives = append(c.directives, directive)
+		c.sources[directive] = nil
+	}
+	c.sources[directive] = append(c.sources[directive], sources...)
+	return c
+}
+
+// Nonce adds the nonce generated for each request to the directives. The nonce
+// is available to the handlers through CSPNonce.
+func (c *CSP) Nonce(directives ...CSPDirective) *CSP {
+	for _, directive := range directives {
+		c.Add(directive)
+		c.nonce[directive] = true
+	}
+	return c
+}
+
+// UpgradeInsecureRequests adds the upgrade-insecure-requests directive.
+func (c *CSP) UpgradeInsecureRequests() *CSP {
+	return c.Add(CSPUpgradeInsecureRequests)
+}
+
+// Build returns the value of the Content-Security-Policy header with the given nonce.
+func (c *CSP) Build(nonce string) string {
+	policy := make([]string, 0, len(c.directives))
+
+	for _, directive := range c.directives {
+		values := append([]string{string(directive)}, c.sources[directive]...)
+		if c.nonce[directive] && nonce != "" {
+			values = append(values, "'nonce-"+nonce+"'")
+		}
+		policy = append(policy, strings.Join(values, " "))
+	}
+
+	return strings.Join(policy, "; ")
+}
+
+// String implements interface fmt.Stringer, returning the policy without nonce.
+func (c *CSP) String() string {
+	return c.Build("")
+}
+
+func (c *CSP) usesNonce() bool {
+	return len(c.nonce) > 0
+}
+
+// CSPNonce returns the nonce of the Content-Security-Policy of the request, to be
+// used in the nonce attribute of the inline scripts and styles.
+func CSPNonce(ctx context.Context) string {
+	nonce, _ := ctx.Value(cspNonceKey).(string)
+	return nonce
+}
+
+func newCSPNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"github.com/tsmweb/go-helper-api/httputil"
+)
+
+// DecompressOptions configures the Decompress middleware.
+type DecompressOptions struct {
+	// MaxSize is the maximum size in bytes of the decompressed body. Reading
+	// beyond it returns an *http.MaxBytesError. It also bounds the window and the
+	// memory of the zstd decoder, which rejects the frames declaring larger ones.
+	// Default 10 MB.
+	MaxSize int64
+
+	// Encodings is the list of encodings accepted. Default gzip, deflate and zstd.
+	Encodings []string
+}
+
+// NewDecompress creates a middleware that transparently decompresses the request
+// bodies sent with the Content-Encoding header. Requests with encodings not
+// accepted are answered with unsupported media type.
+func NewDecompress(opts DecompressOptions) Middleware {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = 10 << 20
+	}
+	if len(opts.Encodings) == 0 {
+		opts.Encodings = []string{EncodingGzip, EncodingDeflate, EncodingZstd}
+	}
+
+	accepted := make(map[string]bool)
+	for _, encoding := range opts.Encodings {
+		accepted[strings.ToLower(encoding)] = true
+	}
+	acceptEncoding := strings.Join(opts.Encodings, ", ")
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encodings := contentEncodings(r.Header.Get("Content-Encoding"))
+			if len(encodings) == 0 || r.Body == nil || r.Body == http.NoBody {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			for _, encoding := range encodings {
+				if !accepted[encoding] {
+					w.Header().Set("Accept-Encoding", acceptEncoding)
+					httputil.RespondWithError(w, http.StatusUnsupportedMediaType,
+						"unsupported content encoding")
+					return
+				}
+			}
+
+			body, err := decompressBody(r.Body, encodings, opts.MaxSize)
+			if err != nil {
+				httputil.RespondWithError(w, http.StatusBadRequest, "invalid compressed body")
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, body, opts.MaxSize)
+			r.ContentLength = -1
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// contentEncodings returns the encodings applied to the body, ignoring "identity".
+func contentEncodings(header string) []string {
+	var encodings []string
+	for _, encoding := range strings.Split(header, ",") {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding != "" && encoding != "identity" {
+			encodings = append(encodings, encoding)
+		}
+	}
+	return encodings
+}
+
+// decompressBody decodes the body in the reverse order in which the encodings were
+// applied. The zstd decoders allocate at most maxSize bytes.
+func decompressBody(body io.ReadCloser, encodings []string, maxSize int64) (io.ReadCloser, error) {
+	window := uint64(maxSize)
+	if window < zstd.MinWindowSize {
+		window = zstd.MinWindowSize
+	} else if window > zstd.MaxWindowSize {
+		window = zstd.MaxWindowSize
+	}
+
+	rc := &multiCloser{Reader: body, closers: []func(){func() { body.Close() }}}
+
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch encodings[i] {
+		case EncodingGzip:
+			zr, err := gzip.NewReader(rc.Reader)
+			if err != nil {
+				rc.Close()
+				return nil, err
+			}
+			rc.push(zr, func() { zr.Close() })
+
+		case EncodingDeflate:
+			zr, err := deflateReader(rc.Reader)
+			if err != nil {
+				rc.Close()
+				return nil, err
+			}
+			rc.push(zr, func() { zr.Close() })
+
+		case EncodingZstd:
+			zr, err := zstd.NewReader(rc.Reader, zstd.WithDecoderConcurrency(1),
+				zstd.WithDecoderMaxWindow(window), zstd.WithDecoderMaxMemory(uint64(maxSize)))
+			if err != nil {
+				rc.Close()
+				return nil, err
+			}
+			rc.push(zr, zr.Close)
+		}
+	}
+
+	return rc, nil
+}
+
+// deflateReader reads the zlib format (RFC 1950) and, as some clients send it,
+// the raw deflate format (RFC 1951).
+func deflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// multiCloser reads from the last decoder and closes all of them.
+type multiCloser struct {
+	io.Reader
+	closers []func()
+}
+
+func (m *multiCloser) push(r io.Reader, close func()) {
+	m.Reader = r
+	m.closers = append(m.closers, close)
+}
+
+// Close implements interface io.Closer.
+func (m *multiCloser) Close() error {
+	for i := len(m.closers) - 1; i >= 0; i-- {
+		m.closers[i]()
+	}
+	m.closers = nil
+	return nil
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestDecompress(t *testing.T) {
+	body := strings.Repeat(`{"id":1}`, 100)
+
+	encoders := map[string]func(w io.Writer) io.WriteCloser{
+		EncodingGzip:    func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		EncodingDeflate: func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		EncodingZstd: func(w io.Writer) io.WriteCloser {
+			zw, _ := zstd.NewWriter(w)
+			return zw
+		},
+	}
+
+	h := NewDecompress(DecompressOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != body {
+			t.Errorf("body = %q", b)
+		}
+		if r.Header.Get("Content-Encoding") != "" {
+			t.Error("Content-Encoding not removed")
+		}
+	}))
+
+	for encoding, newEncoder := range encoders {
+		t.Run(encoding, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc := newEncoder(&buf)
+			io.WriteString(enc, body)
+			enc.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/", &buf)
+			req.Header.Set("Content-Encoding", encoding)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestDecompress_MaxSize(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(make([]byte, 1<<20))
+	zw.Close()
+
+	h := NewDecompress(DecompressOptions{MaxSize: 1024})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		var maxBytesErr *http.MaxBytesError
+		if !errors.As(err, &maxBytesErr) {
+			t.Errorf("err = %v, want *http.MaxBytesError", err)
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestDecompress_ZstdWindow(t *testing.T) {
+	var buf bytes.Buffer
+	zw, _ := zstd.NewWriter(&buf, zstd.WithWindowSize(4<<20))
+	zw.Write(make([]byte, 32<<10))
+	zw.Flush() // streams the frame, declaring the window of 4 MB, not the small size.
+	zw.Close()
+
+	var err error
+	h := NewDecompress(DecompressOptions{MaxSize: 64 << 10})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err = io.ReadAll(r.Body)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", &buf)
+	req.Header.Set("Content-Encoding", "zstd")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if err == nil && rec.Code != http.StatusBadRequest {
+		t.Error("frame with a window larger than MaxSize was decoded")
+	}
+}
+
+func TestDecompress_Unsupported(t *testing.T) {
+	h := NewDecompress(DecompressOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the handler")
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("data"))
+	req.Header.Set("Content-Encoding", `br", "x": "`)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Errorf("body = %s: %v", rec.Body.String(), err)
+	}
+
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnsupportedMediaType)
+	}
+	if rec.Header().Get("Accept-Encoding") == "" {
+		t.Error("Accept-Encoding not set")
+	}
+}
@@ -1,16 +1,209 @@
 /*
-Package middleware provides settings for CORS, GZIP and JWT token validation.
+Package middleware provides HTTP middlewares for CORS, response compression and
+request decompression, JWT token validation and HTTP Basic authentication, request
+IDs, access logs, panic recovery, rate limiting, load shedding, timeouts, security
+headers, real client IPs, request body limits, ETags, idempotency keys, response
+caching and HTTP metrics.
+
+Chain composes the middlewares. Middlewares with the NextFunc signature, as
+Auth.RequireTokenAuth, are adapted to the Middleware signature, as CORS and GZIP:
+
+	auth := middleware.NewAuth(jwt)
+	common := middleware.NewChain(middleware.CORS, middleware.GZIP)
+	private := common.Append(middleware.FromNextFunc(auth.RequireTokenAuth))
+
+	mux.Handle("/health", common.ThenFunc(health))
+	mux.Handle("/users", private.ThenFunc(users))
+	// ...
+
+RequestID accepts or generates the X-Request-ID of the request and stores it in the
+context, linking the request to the Kafka events and event logs it produces:
+
+	h := middleware.RequestID(h)
+	// ...
+
+NewAccessLog logs the requests as JSON lines or as events (see observability/event):
+
+	accessLog := middleware.NewAccessLog(middleware.AccessLogOptions{
+		Writer:       os.Stdout,
+		JWT:          jwt,
+		ExcludePaths: []string{"/health"},
+		SampleRate:   0.5,
+	})
+	h := accessLog(h)
+	// ...
+
+Recover catches the panics of the handlers and sends them as error events:
+
+	h := middleware.Recover(h)
+	// ...
+
+NewRateLimit limits the requests of each client, identified by IP, API key or
+token subject:
+
+	rateLimit := middleware.NewRateLimit(middleware.RateLimitOptions{
+		Algorithm: middleware.SlidingWindow,
+		Limit:     100,
+		Window:    time.Minute,
+		KeyFunc:   middleware.KeyByJWTSubject(jwt, "sub"),
+	})
+	h := rateLimit(h)
+	// ...
+
+NewTimeout sets the deadline of the request context, shrunk by the X-Request-Timeout
+header of the upstream service, and responds with an error when the handler overruns it:
+
+	timeout := middleware.NewTimeout(middleware.TimeoutOptions{Timeout: 5 * time.Second})
+	h := timeout(h)
+	// ...
+
+	// propagates the remaining time to the downstream service.
+	middleware.SetTimeoutHeader(r.Context(), downstreamReq)
+
+SecureHeaders sets HSTS, X-Content-Type-Options, X-Frame-Options, Content-Security-Policy,
+Referrer-Policy and Cross-Origin-Opener-Policy. The CSP may use a nonce per request:
+
+	opts := middleware.DefaultSecureHeadersOptions
+	opts.CSP = middleware.NewCSP().
+		Add(middleware.CSPDefaultSrc, middleware.CSPSelf).
+		Nonce(middleware.CSPScriptSrc)
+	h := middleware.NewSecureHeaders(opts)(h)
+	// ...
+
+	nonce := middleware.CSPNonce(r.Context()) // in the handler
+	// ...
+
+ETag sets the ETag of the GET responses and answers the conditional requests with
+not modified. NewETag also evaluates If-Match on PUT, PATCH and DELETE requests:
+
+	etag := middleware.NewETag(middleware.ETagOptions{
+		CurrentETag: func(r *http.Request) (string, error) {
+			// returns the ETag of the stored resource.
+		},
+	})
+	h := etag(h)
+	// ...
+
+NewIdempotency stores the first response of the requests sent with an Idempotency-Key
+header and replays it to the retries:
+
+	idempotency := middleware.NewIdempotency(middleware.IdempotencyOptions{
+		Scope:   middleware.KeyByJWTSubject(jwt, "sub"),
+		LockTTL: 30 * time.Second,
+	})
+	h := idempotency(h)
+	// ...
+
+NewCache caches the GET responses honouring Cache-Control and Vary, in an LRU store
+limited in size, and invalidates them by the prefix of the path. The responses to
+requests with credentials are only shared when marked public, unless the JWT
+subject is part of the key:
+
+	cache := middleware.NewCache(middleware.CacheOptions{
+		TTL:                  5 * time.Second,
+		StaleWhileRevalidate: 30 * time.Second,
+	})
+	h := cache.Middleware()(h)
+	// ...
+
+	cache.Invalidate("/users/")
+
+HTTPMetrics records the rate, errors and latency of the requests by method and route,
+published to Apache Kafka by the metric package:
+
+	m := middleware.NewHTTPMetrics(middleware.HTTPMetricsOptions{
+		Route: func(r *http.Request) string { return routePattern(r) }, // as "/users/{id}"
+	})
+	if err := metric.Register(m); err != nil {
+		// ...
+	}
+	h := m.Middleware()(h)
+	// ...
+
+NewLoadShed rejects the requests with service unavailable when the concurrent
+requests or the attached worker pools are saturated, shedding the low priority
+requests first:
+
+	loadShed := middleware.NewLoadShed(middleware.LoadShedOptions{
+		MaxConcurrent: 500,
+		Sources:       []middleware.LoadSource{pool}, // *gopool.Pool or *executor.Executor
+		Priority: func(r *http.Request) middleware.Priority {
+			if r.URL.Path == "/health" {
+				return middleware.PriorityCritical
+			}
+			return middleware.PriorityNormal
+		},
+	})
+	h := loadShed(h)
+	// ...
+
+NewRealIP resolves the IP address of the client from the forwarding header set by
+trusted proxies, X-Forwarded-For by default, Forwarded or X-Real-IP. It should be the
+first middleware of the chain, since the other middlewares identify the client
+through ClientIP:
+
+	realIP := middleware.NewRealIP(middleware.RealIPOptions{
+		TrustedProxies: []string{"10.0.0.0/8"},
+		Header:         "X-Forwarded-For",
+	})
+	h := realIP(h)
+	// ...
+
+	ip := middleware.ClientIP(r) // in the handler
+	// ...
+
+NewBodyLimit limits the size of the request bodies, by route, and the accepted
+content types:
+
+	bodyLimit := middleware.NewBodyLimit(middleware.BodyLimitOptions{
+		MaxSize:      64 << 10,
+		RouteMaxSize: map[string]int64{"/upload/*": 10 << 20},
+		AllowedTypes: []httputil.MimeType{httputil.MimeApplicationJSON},
+	})
+	h := bodyLimit(h)
+	// ...
 
 CORS sets the accepted headers, permitted sources and methods accepted by the request:
 
 	h := middleware.CORS(h)
 	// ...
 
+NewCORS configures the allowed origins (exact or wildcard subdomain), credentials,
+exposed headers and preflight caching:
+
+	cors := middleware.NewCORS(middleware.CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.com"},
+		AllowedMethods:   []string{"GET", "POST", "PATCH", "DELETE"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{"Location"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	})
+	h := cors(h)
+	// ...
+
 GZIP compress the http responses:
 
 	h := middleware.GZIP(h)
 	// ...
 
+NewCompress negotiates zstd, brotli, gzip or deflate through the Accept-Encoding
+header, skipping small responses and content types already compressed:
+
+	compress := middleware.NewCompress(middleware.CompressOptions{
+		Level:   middleware.CompressionFastest,
+		MinSize: 1400,
+	})
+	h := compress(h)
+	// ...
+
+NewDecompress decompresses the request bodies sent with gzip, deflate or zstd,
+limiting the size of the decompressed body:
+
+	decompress := middleware.NewDecompress(middleware.DecompressOptions{MaxSize: 5 << 20})
+	h := decompress(h)
+	// ...
+
 Auth validates HTTP requests via token JWT:
 
 	var jwt auth.JWT
@@ -20,5 +213,22 @@ Auth validates HTTP requests via token JWT:
 	auth.RequireTokenAuth(w, r, next)
 	// ...
 
+Authentication failures are sent as warning events (see observability/event) and
+IPs or subjects that exceed the failures allowed can be temporarily blocked:
+
+	auth := middleware.NewAuthWithOptions(jwt, middleware.AuthOptions{
+		MaxFailures:     10,
+		LockoutDuration: time.Minute,
+	})
+	// ...
+
+BasicAuth validates HTTP requests via HTTP Basic authentication:
+
+	hash, _ := hashutil.HashBcrypt("secret")
+	basicAuth := middleware.NewBasicAuth(middleware.Credentials{"admin": hash},
+		middleware.BasicAuthOptions{Realm: "metrics"})
+	basicAuth.RequireBasicAuth(w, r, next)
+	// ...
+
  */
 package middleware
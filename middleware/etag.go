@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/httputil"
+	"github.com/tsmweb/go-helper-api/util/hashutil"
+)
+
+// ETagOptions configures the ETag middleware.
+type ETagOptions struct {
+	// Weak generates weak ETags (W/"..."), for responses that are semantically
+	// equivalent but not byte-for-byte identical.
+	Weak bool
+
+	// CurrentETag returns the ETag of the current representation of the resource
+	// to evaluate the If-Match and If-None-Match preconditions of PUT, PATCH and
+	// DELETE requests. It returns an empty string when the resource does not exist.
+	// If nil, the preconditions of these requests are not evaluated.
+	CurrentETag func(r *http.Request) (string, error)
+}
+
+// ETag sets the strong ETag of the responses and handles the conditional requests.
+func ETag(h http.Handler) http.Handler {
+	return NewETag(ETagOptions{})(h)
+}
+
+// NewETag creates a middleware that sets the ETag of the GET and HEAD responses,
+// computed from the response body unless set by the handler, and responds with
+// not modified when the If-None-Match or If-Modified-Since preconditions match.
+// For PUT, PATCH and DELETE requests, it responds with precondition failed when
+// the If-Match or If-None-Match preconditions do not match the current ETag.
+func NewETag(opts ETagOptions) Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead:
+				conditionalGet(h, w, r, opts.Weak)
+
+			case http.MethodPut, http.MethodPatch, http.MethodDelete:
+				if opts.CurrentETag != nil && !preconditionsMatch(w, r, opts.CurrentETag) {
+					return
+				}
+				h.ServeHTTP(w, r)
+
+			default:
+				h.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func conditionalGet(h http.Handler, w http.ResponseWriter, r *http.Request, weak bool) {
+	buf := newResponseBuffer()
+	h.ServeHTTP(buf, r)
+
+	if buf.status != http.StatusOK {
+		buf.writeTo(w)
+		return
+	}
+
+	etag := buf.header.Get("ETag")
+	if etag == "" {
+		hash, err := hashutil.HashSHA256(buf.body.String())
+		if err != nil {
+			buf.writeTo(w)
+			return
+		}
+
+		etag = `"` + hash + `"`
+		if weak {
+			etag = "W/" + etag
+		}
+		buf.header.Set("ETag", etag)
+	}
+
+	if notModified(r, etag, buf.header.Get("Last-Modified")) {
+		header := w.Header()
+		for k, v := range buf.header {
+			header[k] = v
+		}
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	buf.writeTo(w)
+}
+
+// notModified evaluates If-None-Match or, when not sent, If-Modified-Since.
+func notModified(r *http.Request, etag, lastModified string) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatch(inm, etag, false)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified == "" {
+		return false
+	}
+
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// preconditionsMatch evaluates If-Match and If-None-Match against the current ETag
+// of the resource, responding with precondition failed when they do not match.
+func preconditionsMatch(w http.ResponseWriter, r *http.Request, currentETag func(r *http.Request) (string, error)) bool {
+	im := r.Header.Get("If-Match")
+	inm := r.Header.Get("If-None-Match")
+	if im == "" && inm == "" {
+		return true
+	}
+
+	current, err := currentETag(r)
+	if err != nil {
+		httputil.RespondWithError(w, http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError))
+		return false
+	}
+
+	failed := (im != "" && (current == "" || !etagMatch(im, current, true))) ||
+		(inm != "" && current != "" && etagMatch(inm, current, false))
+
+	if failed {
+		httputil.RespondWithError(w, http.StatusPreconditionFailed,
+			http.StatusText(http.StatusPreconditionFailed))
+		return false
+	}
+	return true
+}
+
+// etagMatch reports whether the list of ETags of the header matches the ETag,
+// using the strong or the weak comparison (RFC 7232).
+func etagMatch(header, etag string, strong bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strong && strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if strings.TrimPrefix(candidate, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/httputil"
+)
+
+func TestETag_ConditionalGet(t *testing.T) {
+	h := ETag(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httputil.RespondWithJSON(w, http.StatusOK, map[string]string{"name": "etag"})
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" || rec.Body.Len() == 0 {
+		t.Fatalf("response = %d, ETag = %q, body = %q", rec.Code, etag, rec.Body.String())
+	}
+
+	tests := []struct {
+		ifNoneMatch string
+		status      int
+	}{
+		{etag, http.StatusNotModified},
+		{`"other", W/` + etag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"other"`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-None-Match", tt.ifNoneMatch)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("If-None-Match %s: status = %d, want %d", tt.ifNoneMatch, rec.Code, tt.status)
+		}
+		if tt.status == http.StatusNotModified && rec.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: body = %q, want empty", tt.ifNoneMatch, rec.Body.String())
+		}
+	}
+}
+
+func TestETag_IfModifiedSince(t *testing.T) {
+	modified := time.Date(2022, 9, 1, 10, 0, 0, 0, time.UTC)
+	h := NewETag(ETagOptions{Weak: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		w.Write([]byte("content"))
+	}))
+
+	tests := []struct {
+		since  time.Time
+		status int
+	}{
+		{modified, http.StatusNotModified},
+		{modified.Add(-time.Hour), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-Modified-Since", tt.since.Format(http.TimeFormat))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("If-Modified-Since %v: status = %d, want %d", tt.since, rec.Code, tt.status)
+		}
+		if etag := rec.Header().Get("ETag"); len(etag) < 2 || etag[:2] != "W/" {
+			t.Errorf("ETag = %q, want weak", etag)
+		}
+	}
+}
+
+func TestETag_IfMatch(t *testing.T) {
+	h := NewETag(ETagOptions{
+		CurrentETag: func(r *http.Request) (string, error) {
+			if r.URL.Path == "/missing" {
+				return "", nil
+			}
+			return `"v2"`, nil
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		method string
+		path   string
+		header string
+		value  string
+		status int
+	}{
+		{http.MethodPut, "/users/1", "If-Match", `"v2"`, http.StatusNoContent},
+		{http.MethodPut, "/users/1", "If-Match", `"v1"`, http.StatusPreconditionFailed},
+		{http.MethodDelete, "/users/1", "If-Match", `W/"v2"`, http.StatusPreconditionFailed},
+		{http.MethodDelete, "/missing", "If-Match", "*", http.StatusPreconditionFailed},
+		{http.MethodPut, "/users/1", "If-None-Match", "*", http.StatusPreconditionFailed},
+		{http.MethodPut, "/missing", "If-None-Match", "*", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set(tt.header, tt.value)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s %s %s %s: status = %d, want %d", tt.method, tt.path, tt.header, tt.value,
+				rec.Code, tt.status)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/httputil"
+	"github.com/tsmweb/go-helper-api/util/hashutil"
+)
+
+var (
+	// ErrIdempotencyInFlight indicates that a request with the same key is being processed.
+	ErrIdempotencyInFlight = errors.New("idempotency key in flight")
+
+	// ErrIdempotencyKeyReused indicates that the key was used by a request with another payload.
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused with another payload")
+)
+
+// IdempotentResponse is the response stored for an idempotency key.
+type IdempotentResponse struct {
+	Status      int
+	Header      http.Header
+	Body        []byte
+	RequestHash string
+}
+
+// IdempotencyStore keeps the responses of the idempotency keys.
+type IdempotencyStore interface {
+	// Begin reserves the key for the request with the given hash for the ttl, after
+	// which the reservation expires, as when the instance crashes. It returns the stored response when the request was already completed,
+	// ErrIdempotencyInFlight when it is being processed and ErrIdempotencyKeyReused
+	// when the key belongs to a request with another hash.
+	Begin(ctx context.Context, key, requestHash string, ttl time.Duration) (*IdempotentResponse, error)
+
+	// Complete stores the response of the key for the ttl.
+	Complete(ctx context.Context, key string, response *IdempotentResponse, ttl time.Duration) error
+
+	// Release removes the reservation of the key, allowing the request to be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyOptions configures the Idempotency middleware.
+type IdempotencyOptions struct {
+	// Header is the header with the idempotency key. Default "Idempotency-Key".
+	Header string
+
+	// Methods is the list of methods handled. Default POST and PATCH.
+	Methods []string
+
+	// Required responds with bad request when the key is not sent.
+	Required bool
+
+	// TTL is the period the response is stored. Default 24 hours.
+	TTL time.Duration
+
+	// LockTTL is the period a key stays reserved while its request is processed,
+	// so that a crashed instance does not block the retries for long. It should be
+	// longer than the slowest request. Default 1 minute.
+	LockTTL time.Duration
+
+	// MaxBodySize is the maximum size in bytes of the request body, read to compute
+	// the request hash. Default 10 MB.
+	MaxBodySize int64
+
+	// Scope scopes the keys by client, so that the keys of different clients do not
+	// collide, as KeyByJWTSubject. Default no scope.
+	Scope KeyFunc
+
+	// Store keeps the responses. Default NewMemoryIdempotencyStore().
+	Store IdempotencyStore
+
+	// OnStoreError is called when the response can not be stored or the key
+	// released, in which case the key stays reserved until LockTTL. Default
+	// writes the error to os.Stderr.
+	OnStoreError func(r *http.Request, err error)
+}
+
+// NewIdempotency creates a middleware that stores the first response of the requests
+// sent with an idempotency key and replays it to the retries with the same key.
+// Retries sent while the first request is being processed are answered with
+// conflict, and keys reused with another payload with unprocessable entity.
+// Responses with status 5xx are not stored, so the request can be retried.
+func NewIdempotency(opts IdempotencyOptions) Middleware {
+	if opts.Header == "" {
+		opts.Header = "Idempotency-Key"
+	}
+	if len(opts.Methods) == 0 {
+		opts.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 24 * time.Hour
+	}
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = time.Minute
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 10 << 20
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryIdempotencyStore()
+	}
+	if opts.OnStoreError == nil {
+		opts.OnStoreError = func(r *http.Request, err error) {
+			fmt.Fprintf(os.Stderr, "idempotency: %s %s: %s\n", r.Method, r.URL.Path, err)
+		}
+	}
+
+	methods := make(map[string]bool)
+	for _, method := range opts.Methods {
+		methods[method] = true
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !methods[r.Method] {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			key := r.Header.Get(opts.Header)
+			if key == "" {
+				if opts.Required {
+					httputil.RespondWithError(w, http.StatusBadRequest, opts.Header+" header is required")
+					return
+				}
+				h.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > 255 {
+				httputil.RespondWithError(w, http.StatusBadRequest, opts.Header+" header is too long")
+				return
+			}
+			if opts.Scope != nil {
+				key = opts.Scope(r) + "|" + key
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, opts.MaxBodySize+1))
+			if err != nil {
+				httputil.RespondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+				return
+			}
+			if int64(len(body)) > opts.MaxBodySize {
+				httputil.RespondWithError(w, http.StatusRequestEntityTooLarge,
+					http.StatusText(http.StatusRequestEntityTooLarge))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash, err := hashutil.HashSHA256(r.Method + " " + r.URL.RequestURI() + "\n" + string(body))
+			if err != nil {
+				httputil.RespondWithError(w, http.StatusInternalServerError,
+					http.StatusText(http.StatusInternalServerError))
+				return
+			}
+
+			stored, err := opts.Store.Begin(r.Context(), key, hash, opts.LockTTL)
+			switch {
+			case errors.Is(err, ErrIdempotencyInFlight):
+				httputil.RespondWithError(w, http.StatusConflict, "a request with the same "+opts.Header+
+					" is being processed")
+				return
+			case errors.Is(err, ErrIdempotencyKeyReused):
+				httputil.RespondWithError(w, http.StatusUnprocessableEntity, opts.Header+
+					" was used with another payload")
+				return
+			case err != nil:
+				httputil.RespondWithError(w, http.StatusInternalServerError,
+					http.StatusText(http.StatusInternalServerError))
+				return
+			case stored != nil:
+				replay(w, stored)
+				return
+			}
+
+			// the key is completed or released even if the client disconnects.
+			ctx, cancel := context.WithTimeout(detachedContext{r.Context()}, 10*time.Second)
+			defer cancel()
+
+			completed := false
+			defer func() {
+				if !completed { // the handler panicked.
+					if err := opts.Store.Release(ctx, key); err != nil {
+						opts.OnStoreError(r, err)
+					}
+				}
+			}()
+
+			buf := newResponseBuffer()
+			h.ServeHTTP(buf, r)
+
+			if buf.status >= http.StatusInternalServerError {
+				err = opts.Store.Release(ctx, key)
+			} else {
+				err = opts.Store.Complete(ctx, key, &IdempotentResponse{
+					Status:      buf.status,
+					Header:      buf.header.Clone(),
+					Body:        buf.body.Bytes(),
+					RequestHash: hash,
+				}, opts.TTL)
+			}
+			completed = true
+			if err != nil {
+				opts.OnStoreError(r, err)
+			}
+
+			buf.writeTo(w)
+		})
+	}
+}
+
+func replay(w http.ResponseWriter, response *IdempotentResponse) {
+	header := w.Header()
+	for k, v := range response.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set("Idempotent-Replayed", "true")
+	w.WriteHeader(response.Status)
+	w.Write(response.Body)
+}
+
+// memoryIdempotencyStore keeps the idempotency keys in memory.
+type memoryIdempotencyStore struct {
+	entries   map[string]*idempotencyEntry
+	lastSweep time.Time
+	mu        sync.Mutex // guard entries and lastSweep
+}
+
+type idempotencyEntry struct {
+	requestHash string
+	response    *IdempotentResponse // nil while in flight.
+	expiresAt   time.Time
+}
+
+// NewMemoryIdempotencyStore creates an IdempotencyStore that keeps the keys in memory.
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotencyStore{
+		entries:   make(map[string]*idempotencyEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// Begin implements interface IdempotencyStore.
+func (m *memoryIdempotencyStore) Begin(_ context.Context, key, requestHash string,
+	ttl time.Duration) (*IdempotentResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+
+	e, ok := m.entries[key]
+	if !ok || now.After(e.expiresAt) {
+		m.entries[key] = &idempotencyEntry{requestHash: requestHash, expiresAt: now.Add(ttl)}
+		return nil, nil
+	}
+
+	if e.requestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if e.response == nil {
+		return nil, ErrIdempotencyInFlight
+	}
+	return e.response, nil
+}
+
+// Complete implements interface IdempotencyStore.
+func (m *memoryIdempotencyStore) Complete(_ context.Context, key string, response *IdempotentResponse,
+	ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries[key] = &idempotencyEntry{
+		requestHash: response.RequestHash,
+		response:    response,
+		expiresAt:   time.Now().Add(ttl),
+	}
+	return nil
+}
+
+// Release implements interface IdempotencyStore.
+func (m *memoryIdempotencyStore) Release(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.entries[key]; ok && e.response == nil {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// sweep removes the expired keys once a minute.
+func (m *memoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+
+	for key, e := range m.entries {
+		if now.After(e.expiresAt) {
+			delete(m.entries, key)
+		}
+	}
+}
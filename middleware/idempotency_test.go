@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	var calls int32
+	h := NewIdempotency(IdempotencyOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Header().Set("Location", "/users/1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte{byte('0' + n)})
+	}))
+
+	do := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := do("key-1", `{"name":"a"}`)
+	retry := do("key-1", `{"name":"a"}`)
+
+	if first.Code != http.StatusCreated || retry.Code != http.StatusCreated {
+		t.Fatalf("status = %d, %d, want %d", first.Code, retry.Code, http.StatusCreated)
+	}
+	if retry.Body.String() != first.Body.String() || retry.Header().Get("Location") != "/users/1" {
+		t.Errorf("replayed response = %v %q", retry.Header(), retry.Body.String())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("Idempotent-Replayed header not set")
+	}
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+
+	if rec := do("key-1", `{"name":"b"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("key reused: status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	if rec := do("key-2", `{"name":"a"}`); rec.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("new key: status = %d, calls = %d", rec.Code, calls)
+	}
+}
+
+func TestIdempotency_InFlight(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	started, release := make(chan struct{}), make(chan struct{})
+
+	h := NewIdempotency(IdempotencyOptions{Store: store})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+		req.Header.Set("Idempotency-Key", "key")
+		return req
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.ServeHTTP(httptest.NewRecorder(), newRequest())
+		close(done)
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest())
+	if rec.Code != http.StatusConflict {
+		t.Errorf("in flight: status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	close(release)
+	<-done
+
+	// the 5xx response is not stored, so the key is free again.
+	if stored, err := store.Begin(newRequest().Context(), "key", "hash", 0); stored != nil || err != nil {
+		t.Errorf("Begin() = %v, %v, want released key", stored, err)
+	}
+}
+
+// cancelCheckStore fails when called with a cancelled context.
+type cancelCheckStore struct {
+	IdempotencyStore
+	lockTTL time.Duration
+}
+
+func (s *cancelCheckStore) Begin(ctx context.Context, key, requestHash string,
+	ttl time.Duration) (*IdempotentResponse, error) {
+	s.lockTTL = ttl
+	return s.IdempotencyStore.Begin(ctx, key, requestHash, ttl)
+}
+
+func (s *cancelCheckStore) Complete(ctx context.Context, key string, response *IdempotentResponse,
+	ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.IdempotencyStore.Complete(ctx, key, response, ttl)
+}
+
+func TestIdempotency_ClientDisconnected(t *testing.T) {
+	store := &cancelCheckStore{IdempotencyStore: NewMemoryIdempotencyStore()}
+	var storeErr error
+	h := NewIdempotency(IdempotencyOptions{
+		Store:        store,
+		LockTTL:      30 * time.Second,
+		OnStoreError: func(r *http.Request, err error) { storeErr = err },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}")).WithContext(ctx)
+	req.Header.Set("Idempotency-Key", "key")
+	cancel() // the client disconnected.
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if storeErr != nil {
+		t.Errorf("OnStoreError() called with %v", storeErr)
+	}
+	if store.lockTTL != 30*time.Second {
+		t.Errorf("Begin() ttl = %v, want LockTTL", store.lockTTL)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	req.Header.Set("Idempotency-Key", "key")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry: status = %d, want the stored response", rec.Code)
+	}
+}
+
+// failingStore fails to complete the keys.
+type failingStore struct {
+	IdempotencyStore
+}
+
+func (s failingStore) Complete(context.Context, string, *IdempotentResponse, time.Duration) error {
+	return errors.New("store unavailable")
+}
+
+func TestIdempotency_StoreError(t *testing.T) {
+	var storeErr error
+	h := NewIdempotency(IdempotencyOptions{
+		Store:        failingStore{NewMemoryIdempotencyStore()},
+		OnStoreError: func(r *http.Request, err error) { storeErr = err },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	req.Header.Set("Idempotency-Key", "key")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated || storeErr == nil {
+		t.Errorf("status = %d, store error = %v", rec.Code, storeErr)
+	}
+}
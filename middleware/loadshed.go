@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/httputil"
+)
+
+// Priority represents the priority class of a request. Requests of lower
+// priority are shed first.
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+
+	// PriorityCritical requests, as health checks, are never shed.
+	PriorityCritical
+)
+
+// DefaultLoadThresholds are the loads above which the requests of each priority
+// are shed.
+var DefaultLoadThresholds = map[Priority]float64{
+	PriorityLow:      0.5,
+	PriorityNormal:   0.8,
+	PriorityHigh:     1,
+	PriorityCritical: math.Inf(1),
+}
+
+// LoadSource reports the load of a resource used by the handlers, between 0 and 1.
+// gopool.Pool and executor.Executor implement LoadSource.
+type LoadSource interface {
+	Load() float64
+}
+
+// LoadSourceFunc adapts a function to a LoadSource.
+type LoadSourceFunc func() float64
+
+// Load implements interface LoadSource.
+func (f LoadSourceFunc) Load() float64 {
+	return f()
+}
+
+// LoadShedOptions configures the LoadShed middleware.
+type LoadShedOptions struct {
+	// MaxConcurrent is the number of concurrent requests at full load. Zero
+	// does not limit the concurrent requests.
+	MaxConcurrent int
+
+	// Sources are the resources whose load is watched, as the worker pools.
+	Sources []LoadSource
+
+	// Priority returns the priority class of the request. Default PriorityNormal.
+	Priority func(r *http.Request) Priority
+
+	// Thresholds are the loads above which the requests of each priority are
+	// shed. Default DefaultLoadThresholds.
+	Thresholds map[Priority]float64
+
+	// RetryAfter is sent in the Retry-After header of the rejected requests.
+	// Default 1 second.
+	RetryAfter time.Duration
+}
+
+// NewLoadShed creates a middleware that rejects the requests with service
+// unavailable when the load exceeds the threshold of their priority, before they
+// block on saturated resources. The load is the greatest of the fraction of
+// MaxConcurrent in use, counting the request, and the load of the Sources.
+func NewLoadShed(opts LoadShedOptions) Middleware {
+	if opts.Priority == nil {
+		opts.Priority = func(r *http.Request) Priority {
+			return PriorityNormal
+		}
+	}
+	if opts.Thresholds == nil {
+		opts.Thresholds = DefaultLoadThresholds
+	}
+	if opts.RetryAfter <= 0 {
+		opts.RetryAfter = time.Second
+	}
+
+	var inFlight int64
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt64(&inFlight, 1)
+			defer atomic.AddInt64(&inFlight, -1)
+
+			var load float64
+			if opts.MaxConcurrent > 0 {
+				load = float64(n) / float64(opts.MaxConcurrent)
+			}
+			for _, source := range opts.Sources {
+				load = math.Max(load, source.Load())
+			}
+
+			threshold, ok := opts.Thresholds[opts.Priority(r)]
+			if !ok {
+				threshold = 1
+			}
+
+			if load > threshold {
+				w.Header().Set("Retry-After", seconds(opts.RetryAfter))
+				httputil.RespondWithError(w, http.StatusServiceUnavailable,
+					http.StatusText(http.StatusServiceUnavailable))
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
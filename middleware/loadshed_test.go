@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/concurrent/gopool"
+)
+
+func TestLoadShed_MaxConcurrent(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	h := NewLoadShed(LoadShedOptions{
+		MaxConcurrent: 10,
+		Priority: func(r *http.Request) Priority {
+			if r.URL.Path == "/health" {
+				return PriorityCritical
+			}
+			return PriorityNormal
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started <- struct{}{}
+			<-release
+		}
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ { // 80% of the load, the threshold of PriorityNormal.
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+		}()
+		<-started
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("normal: status = %d, Retry-After = %q, want 503 and 1", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("critical: status = %d, want 200", rec.Code)
+	}
+
+	close(release)
+	wg.Wait()
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("after load: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestLoadShed_Sources(t *testing.T) {
+	pool := gopool.New(2, 2)
+	defer pool.Close()
+
+	release := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		pool.Schedule(func(ctx context.Context) { <-release })
+	}
+	defer close(release)
+
+	h := NewLoadShed(LoadShedOptions{
+		Sources: []LoadSource{pool},
+		Priority: func(r *http.Request) Priority {
+			return Priority(len(r.URL.Query().Get("p")))
+		},
+		RetryAfter: 5 * time.Second,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		priority string
+		status   int
+	}{
+		{"", http.StatusServiceUnavailable}, // PriorityLow
+		{"x", http.StatusOK},                // PriorityNormal
+		{"xx", http.StatusOK},               // PriorityHigh
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?p="+tt.priority, nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("priority %d: status = %d, want %d (load %v)", len(tt.priority), rec.Code, tt.status, pool.Load())
+		}
+	}
+
+	loaded := LoadSourceFunc(func() float64 { return 0.9 })
+	h = NewLoadShed(LoadShedOptions{Sources: []LoadSource{pool, loaded}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("status = %d, Retry-After = %q, want 503 and 1", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
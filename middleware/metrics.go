@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/observability/metric"
+)
+
+// HTTPMetricsOptions configures the HTTPMetrics.
+type HTTPMetricsOptions struct {
+	// Name is the key of the metrics published by the metric package. Default "http".
+	Name string
+
+	// Route returns the route of the request, as "/users/{id}", usually the pattern
+	// matched by the router. Routes must not contain identifiers or the paths sent
+	// by the clients, which would create a series of metrics per resource. Default
+	// "unmatched", which records the requests by method only.
+	Route func(r *http.Request) string
+
+	// MaxRoutes is the maximum number of series of method and route. The requests
+	// of new series beyond it are recorded in the route "overflow". Default 500.
+	MaxRoutes int
+
+	// Buckets are the upper bounds in milliseconds of the buckets of the latency
+	// histograms. Default metric.DefaultLatencyBuckets.
+	Buckets []float64
+}
+
+// RouteMetrics are the metrics of the requests of a method and route. The counts
+// are cumulative since the HTTPMetrics was created.
+type RouteMetrics struct {
+	Method   string                   `json:"method"`
+	Route    string                   `json:"route"`
+	Requests uint64                   `json:"requests"`
+	Errors   uint64                   `json:"errors"` // responses with status 5xx.
+	Statuses map[int]uint64           `json:"statuses"`
+	Latency  metric.HistogramSnapshot `json:"latency_ms"`
+}
+
+// HTTPMetrics records the rate, errors and duration (RED) of the requests by
+// method and route. It implements metric.Collector, so the metrics are published
+// with the metrics of localhost:
+//
+//	m := middleware.NewHTTPMetrics(middleware.HTTPMetricsOptions{})
+//	err := metric.Register(m)
+//	// ...
+//	h := m.Middleware()(h)
+type HTTPMetrics struct {
+	opts   HTTPMetricsOptions
+	routes map[routeKey]*routeMetrics
+	mu     sync.RWMutex // guard routes
+}
+
+type routeKey struct {
+	method string
+	route  string
+}
+
+type routeMetrics struct {
+	statuses map[int]uint64
+	mu       sync.Mutex // guard statuses
+	latency  *metric.Histogram
+}
+
+// NewHTTPMetrics creates a HTTPMetrics configured by HTTPMetricsOptions.
+func NewHTTPMetrics(opts HTTPMetricsOptions) *HTTPMetrics {
+	if opts.Name == "" {
+		opts.Name = "http"
+	}
+	if opts.Route == nil {
+		opts.Route = func(r *http.Request) string {
+			return "unmatched"
+		}
+	}
+	if opts.MaxRoutes <= 0 {
+		opts.MaxRoutes = 500
+	}
+
+	return &HTTPMetrics{
+		opts:   opts,
+		routes: make(map[routeKey]*routeMetrics),
+	}
+}
+
+// Middleware returns the middleware that records the metrics of the requests.
+func (m *HTTPMetrics) Middleware() Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := newResponseWriter(w)
+			h.ServeHTTP(rw.writer(), r)
+
+			m.observe(routeKey{metricsMethod(r.Method), m.opts.Route(r)}, rw.Status(), time.Since(start))
+		})
+	}
+}
+
+func (m *HTTPMetrics) observe(key routeKey, status int, latency time.Duration) {
+	m.mu.RLock()
+	rm, ok := m.routes[key]
+	m.mu.RUnlock()
+
+	if !ok {
+		m.mu.Lock()
+		if rm, ok = m.routes[key]; !ok && len(m.routes) >= m.opts.MaxRoutes {
+			key.route = "overflow"
+			rm, ok = m.routes[key]
+		}
+		if !ok {
+			rm = &routeMetrics{
+				statuses: make(map[int]uint64),
+				latency:  metric.NewHistogram(m.opts.Buckets),
+			}
+			m.routes[key] = rm
+		}
+		m.mu.Unlock()
+	}
+
+	rm.mu.Lock()
+	rm.statuses[status]++
+	rm.mu.Unlock()
+
+	rm.latency.Observe(float64(latency.Microseconds()) / 1000)
+}
+
+// metricsMethod returns the method of the request, or "OTHER" for the methods not
+// defined by the HTTP specification, which are sent by the clients.
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
+
+// Name implements interface metric.Collector.
+func (m *HTTPMetrics) Name() string {
+	return m.opts.Name
+}
+
+// Collect implements interface metric.Collector, returning the RouteMetrics sorted
+// by route and method.
+func (m *HTTPMetrics) Collect() interface{} {
+	return m.Snapshot()
+}
+
+// Snapshot returns the RouteMetrics sorted by route and method.
+func (m *HTTPMetrics) Snapshot() []RouteMetrics {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot := make([]RouteMetrics, 0, len(m.routes))
+	for key, rm := range m.routes {
+		s := RouteMetrics{
+			Method:   key.method,
+			Route:    key.route,
+			Statuses: make(map[int]uint64),
+			Latency:  rm.latency.Snapshot(),
+		}
+
+		rm.mu.Lock()
+		for status, n := range rm.statuses {
+			s.Statuses[status] = n
+			s.Requests += n
+			if status >= http.StatusInternalServerError {
+				s.Errors += n
+			}
+		}
+		rm.mu.Unlock()
+
+		snapshot = append(snapshot, s)
+	}
+
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Route != snapshot[j].Route {
+			return snapshot[i].Route < snapshot[j].Route
+		}
+		return snapshot[i].Method < snapshot[j].Method
+	})
+	return snapshot
+}
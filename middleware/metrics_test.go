@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tsmweb/go-helper-api/observability/metric"
+)
+
+func TestHTTPMetrics(t *testing.T) {
+	m := NewHTTPMetrics(HTTPMetricsOptions{
+		Route: func(r *http.Request) string {
+			if strings.HasPrefix(r.URL.Path, "/users/") {
+				return "/users/{id}"
+			}
+			return r.URL.Path
+		},
+	})
+	h := m.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/users/3" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/users/1"},
+		{http.MethodGet, "/users/2"},
+		{http.MethodGet, "/users/3"},
+		{http.MethodPost, "/users"},
+	}
+	for _, req := range requests {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	snapshot := m.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("Snapshot() = %+v, want 2 routes", snapshot)
+	}
+
+	post, get := snapshot[0], snapshot[1]
+	if post.Method != http.MethodPost || post.Route != "/users" || post.Requests != 1 || post.Errors != 0 {
+		t.Errorf("POST /users = %+v", post)
+	}
+	if get.Method != http.MethodGet || get.Route != "/users/{id}" || get.Requests != 3 || get.Errors != 1 {
+		t.Errorf("GET /users/{id} = %+v", get)
+	}
+	if get.Statuses[http.StatusOK] != 2 || get.Statuses[http.StatusInternalServerError] != 1 {
+		t.Errorf("GET /users/{id} statuses = %v", get.Statuses)
+	}
+	if get.Latency.Count != 3 || len(get.Latency.Counts) != len(metric.DefaultLatencyBuckets)+1 {
+		t.Errorf("GET /users/{id} latency = %+v", get.Latency)
+	}
+
+	if err := metric.Register(m); err != nil {
+		t.Fatal(err)
+	}
+	defer metric.Unregister(m.Name())
+
+	if _, ok := metric.Collect()["http"].([]RouteMetrics); !ok {
+		t.Errorf("metric.Collect() = %v", metric.Collect())
+	}
+}
+
+func TestHTTPMetrics_Cardinality(t *testing.T) {
+	m := NewHTTPMetrics(HTTPMetricsOptions{})
+	h := m.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, path := range []string{"/a", "/b", "/.env", "/wp-login.php"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("SCAN", "/", nil))
+
+	snapshot := m.Snapshot()
+	if len(snapshot) != 2 || snapshot[0].Method != http.MethodGet || snapshot[0].Route != "unmatched" ||
+		snapshot[0].Requests != 4 || snapshot[1].Method != "OTHER" {
+		t.Errorf("Snapshot() = %+v, want GET and OTHER unmatched", snapshot)
+	}
+
+	m = NewHTTPMetrics(HTTPMetricsOptions{
+		Route:     func(r *http.Request) string { return r.URL.Path },
+		MaxRoutes: 2,
+	})
+	h = m.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, path := range []string{"/a", "/b", "/c", "/d", "/a"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	snapshot = m.Snapshot()
+	if len(snapshot) != 3 || snapshot[0].Route != "/a" || snapshot[0].Requests != 2 ||
+		snapshot[2].Route != "overflow" || snapshot[2].Requests != 2 {
+		t.Errorf("Snapshot() = %+v, want /a, /b and overflow", snapshot)
+	}
+}
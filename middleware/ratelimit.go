@@ -0,0 +1,321 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/auth"
+	"github.com/tsmweb/go-helper-api/httputil"
+)
+
+// RateLimitAlgorithm represents the algorithm used to limit the requests.
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket allows bursts of up to Limit requests, restoring Limit
+	// requests per Window at a constant rate.
+	TokenBucket RateLimitAlgorithm = iota
+
+	// SlidingWindow allows Limit requests in any period of Window, weighting
+	// the requests of the previous window.
+	SlidingWindow
+)
+
+// RateLimitPolicy defines how many requests are allowed per key.
+type RateLimitPolicy struct {
+	Algorithm RateLimitAlgorithm
+	Limit     int
+	Window    time.Duration
+}
+
+// RateLimitResult is the result of taking a request from the limit of a key.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // time until the limit is fully restored.
+	RetryAfter time.Duration // time until the next request is allowed, when not allowed.
+}
+
+// RateLimitStore keeps the state of the rate limits. Stores shared by several
+// instances of the service, as one backed by Redis, enforce a global limit.
+type RateLimitStore interface {
+	// Take takes a request from the limit of the key.
+	Take(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error)
+}
+
+// KeyFunc returns the key that identifies the client of the request.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP identifies the client by its IP address.
+func KeyByIP() KeyFunc {
+	return func(r *http.Request) string {
+		return "ip:" + ClientIP(r)
+	}
+}
+
+// KeyByHeader identifies the client by the value of the header, such as an API key,
+// when validate reports that it is valid, as a registered API key, or by its IP
+// address otherwise. The header is sent by the client, which would bypass the limit
+// with a new value per request, so validate may only be nil when the middleware
+// sits behind the authentication of the header.
+func KeyByHeader(name string, validate func(value string) bool) KeyFunc {
+	return func(r *http.Request) string {
+		if v := r.Header.Get(name); v != "" && (validate == nil || validate(v)) {
+			return "header:" + v
+		}
+		return "ip:" + ClientIP(r)
+	}
+}
+
+// KeyByJWTSubject identifies the client by the claim of the token, or by its IP
+// address when the request does not have a valid token.
+func KeyByJWTSubject(jwt auth.JWT, claim string) KeyFunc {
+	return func(r *http.Request) string {
+		if sub, err := jwt.GetDataToken(r, claim); err == nil && sub != nil {
+			return "sub:" + fmt.Sprint(sub)
+		}
+		return "ip:" + ClientIP(r)
+	}
+}
+
+// RateLimitOptions configures the RateLimit middleware.
+type RateLimitOptions struct {
+	// Algorithm is the algorithm used to limit the requests. Default TokenBucket.
+	Algorithm RateLimitAlgorithm
+
+	// Limit is the number of requests allowed per Window. Default 60.
+	Limit int
+
+	// Window is the period of the limit. Default 1 minute.
+	Window time.Duration
+
+	// KeyFunc identifies the client. Default KeyByIP().
+	KeyFunc KeyFunc
+
+	// Store keeps the state of the limits. Default NewMemoryRateLimitStore(0).
+	Store RateLimitStore
+}
+
+// NewRateLimit creates a middleware that limits the requests of each client,
+// responding with too many requests when the limit is exceeded. The state of
+// the limit is sent in the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers. Requests are allowed if the store fails.
+func NewRateLimit(opts RateLimitOptions) Middleware {
+	if opts.Limit <= 0 {
+		opts.Limit = 60
+	}
+	if opts.Window <= 0 {
+		opts.Window = time.Minute
+	}
+	if opts.KeyFunc == nil {
+		opts.KeyFunc = KeyByIP()
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryRateLimitStore(0)
+	}
+
+	policy := RateLimitPolicy{Algorithm: opts.Algorithm, Limit: opts.Limit, Window: opts.Window}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := opts.Store.Take(r.Context(), opts.KeyFunc(r), policy)
+			if err != nil {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", seconds(result.Reset))
+
+			if !result.Allowed {
+				header.Set("Retry-After", seconds(result.RetryAfter))
+				httputil.RespondWithError(w, http.StatusTooManyRequests,
+					http.StatusText(http.StatusTooManyRequests))
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds formats the duration in seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// memoryRateLimitStore keeps the rate limits in memory.
+type memoryRateLimitStore struct {
+	idleTimeout time.Duration
+	limits      map[RateLimitPolicy]map[string]*rateLimitState
+	lastSweep   time.Time
+	mu          sync.Mutex // guard limits and lastSweep
+	now         func() time.Time
+}
+
+type rateLimitState struct {
+	tokens  float64   // token bucket: tokens available.
+	start   time.Time // sliding window: start of the current window.
+	prev    int       // sliding window: requests of the previous window.
+	curr    int       // sliding window: requests of the current window.
+	lastUse time.Time
+}
+
+// NewMemoryRateLimitStore creates a RateLimitStore that keeps the limits in memory,
+// evicting the keys idle for longer than idleTimeout and the window of its policy.
+// Default idleTimeout 10 minutes.
+func NewMemoryRateLimitStore(idleTimeout time.Duration) RateLimitStore {
+	if idleTimeout <= 0 {
+		idleTimeout = 10 * time.Minute
+	}
+
+	return &memoryRateLimitStore{
+		idleTimeout: idleTimeout,
+		limits:      make(map[RateLimitPolicy]map[string]*rateLimitState),
+		lastSweep:   time.Now(),
+		now:         time.Now,
+	}
+}
+
+// Take implements interface RateLimitStore.
+func (m *memoryRateLimitStore) Take(_ context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error) {
+	if policy.Limit <= 0 || policy.Window <= 0 {
+		return RateLimitResult{}, fmt.Errorf("invalid rate limit policy: %+v", policy)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	states, ok := m.limits[policy]
+	if !ok {
+		states = make(map[string]*rateLimitState)
+		m.limits[policy] = states
+	}
+
+	state, ok := states[key]
+	if !ok {
+		state = &rateLimitState{tokens: float64(policy.Limit), start: now, lastUse: now}
+		states[key] = state
+	}
+
+	var result RateLimitResult
+	if policy.Algorithm == SlidingWindow {
+		result = state.slidingWindow(policy, now)
+	} else {
+		result = state.tokenBucket(policy, now)
+	}
+	state.lastUse = now
+
+	return result, nil
+}
+
+func (s *rateLimitState) tokenBucket(policy RateLimitPolicy, now time.Time) RateLimitResult {
+	limit := float64(policy.Limit)
+	rate := limit / policy.Window.Seconds() // tokens per second
+
+	s.tokens = math.Min(limit, s.tokens+now.Sub(s.lastUse).Seconds()*rate)
+
+	result := RateLimitResult{Limit: policy.Limit}
+	if s.tokens >= 1 {
+		s.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - s.tokens) / rate)
+	}
+
+	result.Remaining = int(s.tokens)
+	result.Reset = secondsToDuration((limit - s.tokens) / rate)
+	return result
+}
+
+func (s *rateLimitState) slidingWindow(policy RateLimitPolicy, now time.Time) RateLimitResult {
+	window := policy.Window
+	limit := float64(policy.Limit)
+
+	if elapsed := now.Sub(s.start); elapsed >= window {
+		windows := elapsed / window
+		if windows == 1 {
+			s.prev = s.curr
+		} else {
+			s.prev = 0
+		}
+		s.curr = 0
+		s.start = s.start.Add(windows * window)
+	}
+
+	elapsed := now.Sub(s.start)
+	weight := 1 - float64(elapsed)/float64(window)
+	estimate := float64(s.prev)*weight + float64(s.curr)
+
+	result := RateLimitResult{Limit: policy.Limit}
+	if estimate+1 <= limit {
+		s.curr++
+		estimate++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = s.retryAfter(window, elapsed, limit)
+	}
+
+	result.Remaining = int(math.Max(0, limit-estimate))
+	switch {
+	case s.curr > 0: // the current requests are weighted until the end of the next window.
+		result.Reset = 2*window - elapsed
+	case s.prev > 0:
+		result.Reset = window - elapsed
+	}
+	return result
+}
+
+// retryAfter returns the time until the estimate of the sliding window allows one more request.
+func (s *rateLimitState) retryAfter(window, elapsed time.Duration, limit float64) time.Duration {
+	free := limit - 1 - float64(s.curr)
+	if free >= 0 && s.prev > 0 {
+		// the previous window weighs less over time: prev * (1 - t/window) <= free
+		t := time.Duration(float64(window) * (1 - free/float64(s.prev)))
+		return t - elapsed
+	}
+
+	// in the next window the current requests are weighted: curr * (1 - t/window) <= limit - 1
+	t := time.Duration(float64(window) * (1 - (limit-1)/float64(s.curr)))
+	return window - elapsed + t
+}
+
+// sweep removes the keys idle for longer than the idle timeout and the window of
+// their policy.
+func (m *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < m.idleTimeout {
+		return
+	}
+	m.lastSweep = now
+
+	for policy, states := range m.limits {
+		idle := m.idleTimeout
+		if policy.Window*2 > idle {
+			idle = policy.Window * 2
+		}
+
+		for key, state := range states {
+			if now.Sub(state.lastUse) > idle {
+				delete(states, key)
+			}
+		}
+		if len(states) == 0 {
+			delete(m.limits, policy)
+		}
+	}
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
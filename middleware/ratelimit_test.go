@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit_TokenBucket(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryRateLimitStore(0).(*memoryRateLimitStore)
+	store.now = func() time.Time { return now }
+	policy := RateLimitPolicy{Algorithm: TokenBucket, Limit: 3, Window: 3 * time.Second}
+
+	for i := 0; i < 3; i++ {
+		if r, _ := store.Take(context.Background(), "key", policy); !r.Allowed || r.Remaining != 2-i {
+			t.Fatalf("request %d: %+v", i, r)
+		}
+	}
+
+	r, _ := store.Take(context.Background(), "key", policy)
+	if r.Allowed || r.RetryAfter != time.Second {
+		t.Fatalf("exceeded: %+v", r)
+	}
+
+	now = now.Add(time.Second)
+	if r, _ = store.Take(context.Background(), "key", policy); !r.Allowed {
+		t.Errorf("after refill: %+v", r)
+	}
+
+	if r, _ = store.Take(context.Background(), "other", policy); !r.Allowed || r.Remaining != 2 {
+		t.Errorf("other key: %+v", r)
+	}
+}
+
+func TestRateLimit_SlidingWindow(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryRateLimitStore(0).(*memoryRateLimitStore)
+	store.now = func() time.Time { return now }
+	policy := RateLimitPolicy{Algorithm: SlidingWindow, Limit: 4, Window: 10 * time.Second}
+
+	for i := 0; i < 4; i++ {
+		if r, _ := store.Take(context.Background(), "key", policy); !r.Allowed {
+			t.Fatalf("request %d: %+v", i, r)
+		}
+	}
+	if r, _ := store.Take(context.Background(), "key", policy); r.Allowed || r.RetryAfter != 12500*time.Millisecond {
+		t.Fatalf("exceeded: %+v", r)
+	}
+
+	// the 4 requests of the previous window weigh 2 in the middle of the next window.
+	now = now.Add(15 * time.Second)
+	for i := 0; i < 2; i++ {
+		if r, _ := store.Take(context.Background(), "key", policy); !r.Allowed {
+			t.Fatalf("next window request %d: %+v", i, r)
+		}
+	}
+	if r, _ := store.Take(context.Background(), "key", policy); r.Allowed {
+		t.Fatalf("next window exceeded: %+v", r)
+	}
+}
+
+func TestRateLimit_Middleware(t *testing.T) {
+	h := NewRateLimit(RateLimitOptions{
+		Limit:   1,
+		KeyFunc: KeyByHeader("X-API-Key", func(v string) bool { return v == "a" || v == "b" }),
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-API-Key", apiKey)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do("a"); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "1" {
+		t.Fatalf("first request: status = %d, headers = %v", rec.Code, rec.Header())
+	}
+
+	rec := do("a")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if rec.Header().Get("Retry-After") != "60" || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("headers = %v", rec.Header())
+	}
+
+	if rec = do("b"); rec.Code != http.StatusOK {
+		t.Errorf("other key: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	// invalid keys are limited by IP address, so random keys do not bypass the limit.
+	if rec = do("random-1"); rec.Code != http.StatusOK {
+		t.Errorf("invalid key: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec = do("random-2"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("another invalid key: status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+}
@@ -0,0 +1,253 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RealIPOptions configures the RealIP middleware.
+type RealIPOptions struct {
+	// TrustedProxies is the list of IP addresses and CIDRs, as "10.0.0.0/8", of the
+	// proxies whose forwarding headers are trusted. Without trusted proxies the
+	// headers are ignored.
+	TrustedProxies []string
+
+	// Header is the forwarding header set by the trusted proxies: "X-Forwarded-For",
+	// with X-Forwarded-Proto and X-Forwarded-Host, "Forwarded" or "X-Real-IP".
+	// Only this header is read, as the proxies may pass the others sent by the
+	// client. Default "X-Forwarded-For".
+	Header string
+
+	// ForwardedURL rewrites the scheme and host of the request with the ones
+	// forwarded by the trusted proxies: the proto and host of the Forwarded element
+	// of the client, or the last values of X-Forwarded-Proto and X-Forwarded-Host.
+	// Enable it only when the proxies replace these values, as some pass the ones
+	// sent by the client, which would inject the host of the absolute URLs.
+	ForwardedURL bool
+}
+
+// NewRealIP creates a middleware that resolves the IP address of the client from the
+// forwarding header of RealIPOptions, when the request comes from a trusted proxy.
+// The addresses are read from right to left, skipping the trusted proxies, so the
+// addresses forged by the client are ignored. With ForwardedURL, the scheme and host
+// of the request are rewritten with the forwarded ones. The IP address is available
+// through ClientIP, also used by the other middlewares of this package.
+//
+// NewRealIP panics if a trusted proxy is not an IP address or CIDR, or the header
+// is not supported.
+func NewRealIP(opts RealIPOptions) Middleware {
+	header := http.CanonicalHeaderKey(opts.Header)
+	switch header {
+	case "":
+		header = "X-Forwarded-For"
+	case "X-Forwarded-For", "Forwarded", "X-Real-Ip":
+	default:
+		panic("middleware: unsupported forwarding header " + opts.Header)
+	}
+
+	trusted := make([]*net.IPNet, 0, len(opts.TrustedProxies))
+	for _, proxy := range opts.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			panic(err)
+		}
+		trusted = append(trusted, ipNet)
+	}
+
+	ri := &realIP{trusted: trusted, header: header}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, ok := ri.resolve(r)
+			if !ok {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), clientIPKey, client.ip.String())
+			if !opts.ForwardedURL {
+				h.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			// the request is cloned to not change the URL of the caller.
+			r = r.Clone(ctx)
+			if client.proto == "http" || client.proto == "https" {
+				r.URL.Scheme = client.proto
+			}
+			if validHost(client.host) {
+				r.Host = client.host
+				r.URL.Host = client.host
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the IP address of the client that sent the request, resolved
+// by the RealIP middleware, or the IP address of the peer.
+func ClientIP(r *http.Request) string {
+	if ip, ok := ClientIPFromContext(r.Context()); ok {
+		return ip
+	}
+	return peerIP(r)
+}
+
+// ClientIPFromContext returns the IP address of the client resolved by the RealIP
+// middleware.
+func ClientIPFromContext(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(clientIPKey).(string)
+	return ip, ok
+}
+
+// peerIP returns the IP address of the peer that sent the request.
+func peerIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type realIP struct {
+	trusted []*net.IPNet
+	header  string
+}
+
+// hop is an address of the forwarding chain, with the scheme and host forwarded.
+// The IP address is nil when the address is obfuscated, unknown or invalid.
+type hop struct {
+	ip    net.IP
+	proto string
+	host  string
+}
+
+// resolve returns the hop of the client. The hops are read from right to left up
+// to the first address not trusted; the addresses on its left, which may be forged
+// by the client, are ignored even if invalid. When all addresses are trusted, the
+// client is the leftmost one. An address that can not be resolved before the
+// client, as one obfuscated by a trusted proxy, fails the resolution.
+func (ri *realIP) resolve(r *http.Request) (hop, bool) {
+	peer := net.ParseIP(peerIP(r))
+	if peer == nil || !ri.isTrusted(peer) {
+		return hop{}, false
+	}
+
+	var hops []hop
+	switch ri.header {
+	case "Forwarded":
+		hops = forwardedHops(r.Header.Values("Forwarded"))
+	case "X-Real-Ip":
+		hops = []hop{{ip: parseNode(strings.TrimSpace(r.Header.Get("X-Real-IP")))}}
+	default:
+		hops = xForwardedHops(r.Header)
+	}
+	if len(hops) == 0 {
+		return hop{}, false
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		if hops[i].ip == nil {
+			return hop{}, false
+		}
+		if !ri.isTrusted(hops[i].ip) {
+			return hops[i], true
+		}
+	}
+	return hops[0], true
+}
+
+func (ri *realIP) isTrusted(ip net.IP) bool {
+	for _, ipNet := range ri.trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedHops parses the Forwarded headers (RFC 7239), as in:
+//
+//	Forwarded: for=192.0.2.60;proto=https;host=example.com, for="[2001:db8::1]:4711"
+func forwardedHops(values []string) []hop {
+	var hops []hop
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			var h hop
+			for _, pair := range strings.Split(element, ";") {
+				name, v, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				v = strings.Trim(v, `"`)
+
+				switch strings.ToLower(name) {
+				case "for":
+					h.ip = parseNode(v)
+				case "proto":
+					h.proto = strings.ToLower(v)
+				case "host":
+					h.host = v
+				}
+			}
+			hops = append(hops, h)
+		}
+	}
+	return hops
+}
+
+// xForwardedHops parses the X-Forwarded-For header, with the last values of the
+// X-Forwarded-Proto and X-Forwarded-Host headers, set by the nearest proxy. These
+// are not matched by position, as the proxies may pass the values of the client.
+func xForwardedHops(header http.Header) []hop {
+	addrs := splitValues(header.Values("X-Forwarded-For"))
+	proto := strings.ToLower(lastValue(splitValues(header.Values("X-Forwarded-Proto"))))
+	host := lastValue(splitValues(header.Values("X-Forwarded-Host")))
+
+	hops := make([]hop, len(addrs))
+	for i, addr := range addrs {
+		hops[i] = hop{ip: parseNode(addr), proto: proto, host: host}
+	}
+	return hops
+}
+
+func splitValues(values []string) []string {
+	var split []string
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			split = append(split, strings.TrimSpace(v))
+		}
+	}
+	return split
+}
+
+func lastValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// parseNode parses an IP address with an optional port, as "192.0.2.60:8080" or
+// "[2001:db8::1]:4711".
+func parseNode(node string) net.IP {
+	if ip := net.ParseIP(node); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return net.ParseIP(host)
+	}
+	return net.ParseIP(strings.Trim(node, "[]"))
+}
+
+// validHost reports whether host is a host name with an optional port.
+func validHost(host string) bool {
+	return host != "" && !strings.ContainsAny(host, " /\\@?#")
+}
@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		trusted    string // the forwarding header of RealIPOptions.
+		url        bool   // RealIPOptions.ForwardedURL.
+		header     http.Header
+		ip         string
+		scheme     string
+		host       string
+	}{
+		{
+			name:       "untrusted peer",
+			remoteAddr: "203.0.113.7:1234",
+			header:     http.Header{"X-Forwarded-For": {"198.51.100.1"}},
+			ip:         "203.0.113.7",
+			host:       "example.com",
+		},
+		{
+			name:       "no headers",
+			remoteAddr: "10.0.0.1:1234",
+			ip:         "10.0.0.1",
+			host:       "example.com",
+		},
+		{
+			name:       "x-forwarded-for",
+			remoteAddr: "10.0.0.1:1234",
+			url:        true,
+			header: http.Header{
+				"X-Forwarded-For":   {"198.51.100.9, 198.51.100.1, 10.0.0.2"},
+				"X-Forwarded-Proto": {"https"},
+				"X-Forwarded-Host":  {"api.example.com"},
+			},
+			ip:     "198.51.100.1", // 198.51.100.9 may be forged by the client.
+			scheme: "https",
+			host:   "api.example.com",
+		},
+		{
+			name:       "forged leftmost entry",
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"garbage, 203.0.113.5"}},
+			ip:         "203.0.113.5",
+			host:       "example.com",
+		},
+		{
+			name:       "invalid entry of trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"203.0.113.5, garbage"}},
+			ip:         "10.0.0.1",
+			host:       "example.com",
+		},
+		{
+			name:       "forwarded url disabled",
+			remoteAddr: "10.0.0.1:1234",
+			header: http.Header{
+				"X-Forwarded-For":   {"198.51.100.1"},
+				"X-Forwarded-Proto": {"https"},
+				"X-Forwarded-Host":  {"evil.com"},
+			},
+			ip:   "198.51.100.1",
+			host: "example.com",
+		},
+		{
+			name:       "x-forwarded-host of the client",
+			remoteAddr: "10.0.0.1:1234",
+			url:        true,
+			header: http.Header{
+				"X-Forwarded-For":  {"198.51.100.1, 10.0.0.2"},
+				"X-Forwarded-Host": {"evil.com, api.example.com"},
+			},
+			ip:   "198.51.100.1",
+			host: "api.example.com",
+		},
+		{
+			name:       "all trusted",
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"10.0.0.3", "10.0.0.2"}},
+			ip:         "10.0.0.3",
+			host:       "example.com",
+		},
+		{
+			name:       "forged forwarded",
+			remoteAddr: "10.0.0.1:1234",
+			header: http.Header{
+				"Forwarded":       {"for=1.2.3.4"},
+				"X-Forwarded-For": {"203.0.113.9"},
+			},
+			ip:   "203.0.113.9",
+			host: "example.com",
+		},
+		{
+			name:       "forged x-real-ip",
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Real-Ip": {"1.2.3.4"}},
+			ip:         "10.0.0.1",
+			host:       "example.com",
+		},
+		{
+			name:       "forwarded",
+			remoteAddr: "[2001:db8::10]:443",
+			trusted:    "Forwarded",
+			url:        true,
+			header: http.Header{
+				"Forwarded":       {`for="[2001:db8::1]:4711";proto=https;host=www.example.com, for=10.0.0.2`},
+				"X-Forwarded-For": {"198.51.100.1"},
+			},
+			ip:     "2001:db8::1",
+			scheme: "https",
+			host:   "www.example.com",
+		},
+		{
+			name:       "obfuscated forwarded",
+			remoteAddr: "10.0.0.1:1234",
+			trusted:    "Forwarded",
+			header: http.Header{
+				"Forwarded":       {"for=_hidden"},
+				"X-Forwarded-For": {"198.51.100.1"},
+			},
+			ip:   "10.0.0.1",
+			host: "example.com",
+		},
+		{
+			name:       "forged obfuscated forwarded",
+			remoteAddr: "10.0.0.1:1234",
+			trusted:    "Forwarded",
+			header:     http.Header{"Forwarded": {"for=unknown, for=198.51.100.1"}},
+			ip:         "198.51.100.1",
+			host:       "example.com",
+		},
+		{
+			name:       "x-real-ip",
+			remoteAddr: "10.0.0.1:1234",
+			trusted:    "x-real-ip",
+			header:     http.Header{"X-Real-Ip": {"198.51.100.1"}},
+			ip:         "198.51.100.1",
+			host:       "example.com",
+		},
+		{
+			name:       "invalid host",
+			remoteAddr: "10.0.0.1:1234",
+			url:        true,
+			header: http.Header{
+				"X-Forwarded-For":  {"198.51.100.1"},
+				"X-Forwarded-Host": {"evil.com/path"},
+			},
+			ip:   "198.51.100.1",
+			host: "example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			realIP := NewRealIP(RealIPOptions{
+				TrustedProxies: []string{"10.0.0.0/8", "2001:db8::10"},
+				Header:         tt.trusted,
+				ForwardedURL:   tt.url,
+			})
+
+			var ip, scheme, host string
+			h := realIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ip, scheme, host = ClientIP(r), r.URL.Scheme, r.Host
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Host = "example.com"
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			if ip != tt.ip {
+				t.Errorf("ClientIP() = %q, want %q", ip, tt.ip)
+			}
+			if scheme != tt.scheme || host != tt.host {
+				t.Errorf("scheme = %q, host = %q, want %q and %q", scheme, host, tt.scheme, tt.host)
+			}
+			if req.Host != "example.com" || req.URL.Scheme != "" {
+				t.Errorf("request of the caller changed: host = %q, scheme = %q", req.Host, req.URL.Scheme)
+			}
+		})
+	}
+}
+
+func TestRealIP_InvalidProxy(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewRealIP() did not panic with an invalid proxy")
+		}
+	}()
+	NewRealIP(RealIPOptions{TrustedProxies: []string{"proxy.local"}})
+}
+
+func TestRealIP_InvalidHeader(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewRealIP() did not panic with an unsupported header")
+		}
+	}()
+	NewRealIP(RealIPOptions{Header: "X-Client-IP"})
+}
+
+func TestRealIP_RateLimitKey(t *testing.T) {
+	var key string
+	h := NewRealIP(RealIPOptions{TrustedProxies: []string{"127.0.0.1"}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key = KeyByIP()(r)
+		}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if key != "ip:198.51.100.1" {
+		t.Errorf("KeyByIP() = %q, want ip:198.51.100.1", key)
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"runtime/debug"
+
+	"github.com/tsmweb/go-helper-api/httputil"
+	"github.com/tsmweb/go-helper-api/observability/event"
+)
+
+// RecoverOptions configures the Recover middleware.
+type RecoverOptions struct {
+	// Host is reported in the events. Default os.Hostname().
+	Host string
+
+	// Report is called with the value of the panic and its stack trace, in
+	// addition to the error event.
+	Report func(r *http.Request, err interface{}, stack []byte)
+}
+
+// Recover catches the panics of the handler, responds with internal server error
+// and sends an error event with the stack trace.
+func Recover(h http.Handler) http.Handler {
+	return NewRecover(RecoverOptions{})(h)
+}
+
+// NewRecover creates a Recover middleware configured by RecoverOptions.
+func NewRecover(opts RecoverOptions) Middleware {
+	if opts.Host == "" {
+		opts.Host, _ = os.Hostname()
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := newResponseWriter(w)
+
+			defer func() {
+				err := recover()
+				if err == nil {
+					return
+				}
+				if err == http.ErrAbortHandler { // aborts the response on purpose.
+					panic(err)
+				}
+
+				stack := debug.Stack()
+
+				if !rw.Written() {
+					httputil.RespondWithError(rw, http.StatusInternalServerError,
+						http.StatusText(http.StatusInternalServerError))
+				}
+
+				detail := fmt.Sprintf("panic: %v\nmethod=%s path=%s ip=%s\n\n%s",
+					err, r.Method, r.URL.Path, ClientIP(r), stack)
+				_ = event.TrySend(event.NewWithContext(r.Context(), opts.Host, "", "Panic recovered",
+					event.Error, detail))
+
+				if opts.Report != nil {
+					opts.Report(r, err, stack)
+				}
+			}()
+
+			h.ServeHTTP(rw.writer(), r)
+		})
+	}
+}
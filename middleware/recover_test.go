@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	var reported interface{}
+	var stack []byte
+
+	h := NewRecover(RecoverOptions{
+		Report: func(r *http.Request, err interface{}, s []byte) {
+			reported, stack = err, s
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+	if reported != "boom" {
+		t.Errorf("reported = %v, want boom", reported)
+	}
+	if !strings.Contains(string(stack), "recover_test.go") {
+		t.Error("stack trace does not contain the panic origin")
+	}
+}
+
+func TestRecover_ErrAbortHandler(t *testing.T) {
+	h := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Errorf("recover() = %v, want http.ErrAbortHandler", err)
+		}
+	}()
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
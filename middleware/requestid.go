@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/tsmweb/go-helper-api/requestid"
+)
+
+// RequestID accepts the request ID sent in the X-Request-ID header or generates a
+// new one, stores it in the request context and sends it back in the response.
+// Producer.Publish and event.NewWithContext read it from the context.
+func RequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !validRequestID(id) {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		h.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+	})
+}
+
+// validRequestID accepts IDs of up to 128 visible ASCII characters, preventing
+// header and log injection.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' || id[i] == '"' || id[i] == '\\' {
+			return false
+		}
+	}
+	return true
+}
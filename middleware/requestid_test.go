@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tsmweb/go-helper-api/requestid"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"accept incoming", "abc-123", true},
+		{"generate when missing", "", false},
+		{"generate when invalid", "bad id\r\n", false},
+		{"generate when too long", strings.Repeat("a", 129), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = requestid.FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(requestid.Header, tt.incoming)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if got == "" || got != rec.Header().Get(requestid.Header) {
+				t.Fatalf("context ID = %q, header ID = %q", got, rec.Header().Get(requestid.Header))
+			}
+			if tt.keep != (got == tt.incoming) {
+				t.Errorf("ID = %q, incoming %q", got, tt.incoming)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/httputil"
+)
+
+// SecureHeadersOptions configures the SecureHeaders middleware. Empty fields omit
+// their headers.
+type SecureHeadersOptions struct {
+	// HSTSMaxAge is the max-age of the Strict-Transport-Security header.
+	HSTSMaxAge time.Duration
+
+	// HSTSIncludeSubdomains adds the includeSubDomains directive to Strict-Transport-Security.
+	HSTSIncludeSubdomains bool
+
+	// HSTSPreload adds the preload directive to Strict-Transport-Security.
+	HSTSPreload bool
+
+	// ContentTypeNosniff sets X-Content-Type-Options to "nosniff".
+	ContentTypeNosniff bool
+
+	// FrameOptions is the value of X-Frame-Options ("DENY", "SAMEORIGIN").
+	FrameOptions string
+
+	// CSP is the Content-Security-Policy.
+	CSP *CSP
+
+	// CSPReportOnly sends the CSP in the Content-Security-Policy-Report-Only header.
+	CSPReportOnly bool
+
+	// ReferrerPolicy is the value of Referrer-Policy.
+	ReferrerPolicy string
+
+	// PermissionsPolicy is the value of Permissions-Policy, as in "camera=(), geolocation=()".
+	PermissionsPolicy string
+
+	// CrossOriginOpenerPolicy is the value of Cross-Origin-Opener-Policy.
+	CrossOriginOpenerPolicy string
+
+	// CrossOriginEmbedderPolicy is the value of Cross-Origin-Embedder-Policy.
+	CrossOriginEmbedderPolicy string
+}
+
+// DefaultSecureHeadersOptions are the options used by SecureHeaders.
+var DefaultSecureHeadersOptions = SecureHeadersOptions{
+	HSTSMaxAge:              365 * 24 * time.Hour,
+	HSTSIncludeSubdomains:   true,
+	ContentTypeNosniff:      true,
+	FrameOptions:            "DENY",
+	CSP:                     NewCSP().Add(CSPDefaultSrc, CSPSelf).Add(CSPFrameAncestors, CSPNone),
+	ReferrerPolicy:          "strict-origin-when-cross-origin",
+	CrossOriginOpenerPolicy: "same-origin",
+}
+
+// SecureHeaders sets the security headers with the DefaultSecureHeadersOptions.
+func SecureHeaders(h http.Handler) http.Handler {
+	return NewSecureHeaders(DefaultSecureHeadersOptions)(h)
+}
+
+// NewSecureHeaders creates a middleware that sets the security headers configured
+// by SecureHeadersOptions. When the CSP uses a nonce, a new nonce is generated for
+// each request and stored in its context (see CSPNonce).
+func NewSecureHeaders(opts SecureHeadersOptions) Middleware {
+	static := make(http.Header)
+
+	if opts.HSTSMaxAge > 0 {
+		hsts := "max-age=" + strconv.FormatInt(int64(opts.HSTSMaxAge.Seconds()), 10)
+		if opts.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if opts.HSTSPreload {
+			hsts += "; preload"
+		}
+		static.Set("Strict-Transport-Security", hsts)
+	}
+	if opts.ContentTypeNosniff {
+		static.Set("X-Content-Type-Options", "nosniff")
+	}
+	setIfNotEmpty(static, "X-Frame-Options", opts.FrameOptions)
+	setIfNotEmpty(static, "Referrer-Policy", opts.ReferrerPolicy)
+	setIfNotEmpty(static, "Permissions-Policy", opts.PermissionsPolicy)
+	setIfNotEmpty(static, "Cross-Origin-Opener-Policy", opts.CrossOriginOpenerPolicy)
+	setIfNotEmpty(static, "Cross-Origin-Embedder-Policy", opts.CrossOriginEmbedderPolicy)
+
+	cspHeader := "Content-Security-Policy"
+	if opts.CSPReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+	var csp string
+	if opts.CSP != nil && !opts.CSP.usesNonce() {
+		csp = opts.CSP.String()
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			for k, v := range static {
+				header.Set(k, v[0])
+			}
+
+			switch {
+			case csp != "":
+				header.Set(cspHeader, csp)
+
+			case opts.CSP != nil:
+				nonce, err := newCSPNonce()
+				if err != nil {
+					httputil.RespondWithError(w, http.StatusInternalServerError,
+						http.StatusText(http.StatusInternalServerError))
+					return
+				}
+				header.Set(cspHeader, opts.CSP.Build(nonce))
+				r = r.WithContext(context.WithValue(r.Context(), cspNonceKey, nonce))
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func setIfNotEmpty(header http.Header, key, value string) {
+	if value != "" {
+		header.Set(key, value)
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSP_Build(t *testing.T) {
+	csp := NewCSP().
+		Add(CSPDefaultSrc, CSPSelf).
+		Add(CSPImgSrc, CSPSelf, CSPData).
+		Nonce(CSPScriptSrc).
+		Add(CSPScriptSrc, CSPStrictDynamic).
+		UpgradeInsecureRequests()
+
+	want := "default-src 'self'; img-src 'self' data:; script-src 'strict-dynamic' 'nonce-abc'; " +
+		"upgrade-insecure-requests"
+	if got := csp.Build("abc"); got != want {
+		t.Errorf("Build() = %q, want %q", got, want)
+	}
+}
+
+func TestSecureHeaders(t *testing.T) {
+	h := SecureHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	want := map[string]string{
+		"Strict-Transport-Security":  "max-age=31536000; includeSubDomains",
+		"X-Content-Type-Options":     "nosniff",
+		"X-Frame-Options":            "DENY",
+		"Content-Security-Policy":    "default-src 'self'; frame-ancestors 'none'",
+		"Referrer-Policy":            "strict-origin-when-cross-origin",
+		"Cross-Origin-Opener-Policy": "same-origin",
+	}
+	for k, v := range want {
+		if got := rec.Header().Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+}
+
+func TestSecureHeaders_Nonce(t *testing.T) {
+	opts := SecureHeadersOptions{
+		HSTSMaxAge:    time.Hour,
+		HSTSPreload:   true,
+		CSP:           NewCSP().Add(CSPDefaultSrc, CSPSelf).Nonce(CSPScriptSrc),
+		CSPReportOnly: true,
+	}
+
+	var nonces []string
+	h := NewSecureHeaders(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonces = append(nonces, CSPNonce(r.Context()))
+	}))
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if got := rec.Header().Get("Strict-Transport-Security"); got != "max-age=3600; preload" {
+			t.Errorf("Strict-Transport-Security = %q", got)
+		}
+		csp := rec.Header().Get("Content-Security-Policy-Report-Only")
+		if nonces[i] == "" || !strings.Contains(csp, "'nonce-"+nonces[i]+"'") {
+			t.Errorf("Content-Security-Policy-Report-Only = %q, nonce = %q", csp, nonces[i])
+		}
+	}
+
+	if nonces[0] == nonces[1] {
+		t.Error("nonce reused between requests")
+	}
+}
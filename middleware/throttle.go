@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// throttle counts failed attempts per key and blocks the keys that exceed
+// the maximum number of failures within the window. Each new block of the
+// same key doubles the lockout, up to maxLockout.
+type throttle struct {
+	maxFailures int
+	window      time.Duration
+	lockout     time.Duration
+	maxLockout  time.Duration
+
+	entries   map[string]*throttleEntry
+	lastSweep time.Time
+	mu        sync.Mutex // guard entries and lastSweep
+}
+
+type throttleEntry struct {
+	failures     int
+	lockouts     int
+	first        time.Time
+	blockedUntil time.Time
+}
+
+// newThrottle creates a throttle. A maxFailures less than or equal to zero
+// disables the throttle.
+func newThrottle(maxFailures int, window, lockout, maxLockout time.Duration) *throttle {
+	if maxLockout < lockout {
+		maxLockout = lockout
+	}
+
+	return &throttle{
+		maxFailures: maxFailures,
+		window:      window,
+		lockout:     lockout,
+		maxLockout:  maxLockout,
+		entries:     make(map[string]*throttleEntry),
+		lastSweep:   time.Now(),
+	}
+}
+
+// blocked reports whether any of the keys is blocked and for how long.
+func (t *throttle) blocked(keys ...string) (time.Duration, bool) {
+	if t.maxFailures <= 0 {
+		return 0, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+
+	for _, key := range keys {
+		e, ok := t.entries[key]
+		if !ok {
+			continue
+		}
+		if d := e.blockedUntil.Sub(now); d > wait {
+			wait = d
+		}
+	}
+
+	return wait, wait > 0
+}
+
+// fail records a failed attempt for each key and reports whether any of
+// them has been blocked as a result.
+func (t *throttle) fail(keys ...string) bool {
+	if t.maxFailures <= 0 {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.sweep(now)
+	locked := false
+
+	for _, key := range keys {
+		e, ok := t.entries[key]
+		if !ok {
+			e = &throttleEntry{first: now}
+			t.entries[key] = e
+		}
+		if now.Sub(e.first) > t.window {
+			e.failures = 0
+			e.first = now
+		}
+
+		e.failures++
+		if e.failures >= t.maxFailures {
+			e.blockedUntil = now.Add(t.lockoutFor(e.lockouts))
+			e.lockouts++
+			e.failures = 0
+			e.first = now
+			locked = true
+		}
+	}
+
+	return locked
+}
+
+// lockoutFor returns the lockout of a key already blocked the given number of times.
+func (t *throttle) lockoutFor(lockouts int) time.Duration {
+	d := t.lockout
+	for i := 0; i < lockouts && d < t.maxLockout; i++ {
+		d *= 2
+	}
+	if d > t.maxLockout {
+		d = t.maxLockout
+	}
+	return d
+}
+
+// reset forgets the failures recorded for the keys.
+func (t *throttle) reset(keys ...string) {
+	if t.maxFailures <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, key := range keys {
+		delete(t.entries, key)
+	}
+}
+
+// sweep removes the entries that are neither inside the window nor blocked
+// recently enough to be taken into account by the backoff.
+func (t *throttle) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < t.window {
+		return
+	}
+	t.lastSweep = now
+
+	for key, e := range t.entries {
+		if now.Sub(e.first) > t.window && now.After(e.blockedUntil.Add(t.maxLockout)) {
+			delete(t.entries, key)
+		}
+	}
+}
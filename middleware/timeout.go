@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tsmweb/go-helper-api/httputil"
+)
+
+// TimeoutHeader is the header with the time in milliseconds that the upstream
+// service waits for the response.
+const TimeoutHeader = "X-Request-Timeout"
+
+// TimeoutOptions configures the Timeout middleware.
+type TimeoutOptions struct {
+	// Timeout is the maximum duration of the handler. Default 30 seconds.
+	Timeout time.Duration
+
+	// Status is the status sent when the handler overruns, http.StatusServiceUnavailable
+	// (default) or http.StatusGatewayTimeout.
+	Status int
+
+	// Message is the error message sent when the handler overruns. Default the
+	// text of Status.
+	Message string
+
+	// IgnoreUpstream ignores the TimeoutHeader sent by the upstream service.
+	IgnoreUpstream bool
+}
+
+// NewTimeout creates a middleware that runs the handler with a context deadline and
+// responds with an error if the handler overruns it. The deadline is shrunk to the
+// time in the TimeoutHeader, when sent by the upstream service. After the timeout,
+// the writes of the handler fail with http.ErrHandlerTimeout.
+//
+// The response is buffered until the handler returns, so streaming responses are
+// not supported.
+func NewTimeout(opts TimeoutOptions) Middleware {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.Status == 0 {
+		opts.Status = http.StatusServiceUnavailable
+	}
+	if opts.Message == "" {
+		opts.Message = http.StatusText(opts.Status)
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := opts.Timeout
+			if !opts.IgnoreUpstream {
+				if upstream, ok := upstreamTimeout(r); ok && upstream < timeout {
+					timeout = upstream
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{w: w, header: make(http.Header), status: http.StatusOK}
+			done := make(chan struct{})
+			panicChan := make(chan interface{}, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicChan <- p
+					}
+				}()
+				h.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicChan:
+				panic(p)
+
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				dst := w.Header()
+				for k, v := range tw.header {
+					dst[k] = v
+				}
+				w.WriteHeader(tw.status)
+				w.Write(tw.buf.Bytes())
+
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				tw.timedOut = true
+				httputil.RespondWithError(w, opts.Status, opts.Message)
+			}
+		})
+	}
+}
+
+// upstreamTimeout returns the time sent in the TimeoutHeader.
+func upstreamTimeout(r *http.Request) (time.Duration, bool) {
+	v := r.Header.Get(TimeoutHeader)
+	if v == "" {
+		return 0, false
+	}
+
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms < 0 {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// SetTimeoutHeader sets the TimeoutHeader of the request sent to a downstream
+// service with the time remaining until the deadline of ctx.
+func SetTimeoutHeader(ctx context.Context, req *http.Request) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+
+	ms := time.Until(deadline).Milliseconds()
+	if ms < 0 {
+		ms = 0
+	}
+	req.Header.Set(TimeoutHeader, strconv.FormatInt(ms, 10))
+}
+
+// timeoutWriter buffers the response until the handler returns.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+	buf    bytes.Buffer
+
+	mu          sync.Mutex // guard the fields below
+	status      int
+	wroteHeader bool
+	timedOut    bool
+}
+
+// Header implements interface http.ResponseWriter.
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// Write implements interface http.ResponseWriter.
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.wroteHeader = true
+	return tw.buf.Write(p)
+}
+
+// WriteHeader implements interface http.ResponseWriter.
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	tw.status = status
+}
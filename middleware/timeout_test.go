@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	writeErr := make(chan error, 1)
+	h := NewTimeout(TimeoutOptions{Timeout: 20 * time.Millisecond, Status: http.StatusGatewayTimeout})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			time.Sleep(5 * time.Millisecond)
+			_, err := w.Write([]byte("late"))
+			writeErr <- err
+		}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+	}
+	if err := <-writeErr; err != http.ErrHandlerTimeout {
+		t.Errorf("write after timeout: err = %v, want http.ErrHandlerTimeout", err)
+	}
+}
+
+func TestTimeout_Upstream(t *testing.T) {
+	var budget time.Duration
+	h := NewTimeout(TimeoutOptions{Timeout: time.Minute})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline, _ := r.Context().Deadline()
+			budget = time.Until(deadline)
+
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("ok"))
+		}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TimeoutHeader, "500")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if budget <= 0 || budget > 500*time.Millisecond {
+		t.Errorf("budget = %v, want <= 500ms", budget)
+	}
+	if rec.Code != http.StatusAccepted || rec.Body.String() != "ok" || rec.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("response = %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+}
+
+func TestSetTimeoutHeader(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	SetTimeoutHeader(ctx, req)
+
+	d, ok := upstreamTimeout(req)
+	if !ok || d <= time.Second || d > 2*time.Second {
+		t.Errorf("timeout = %v, %v", d, ok)
+	}
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// responseWriter records the status and the number of bytes of the response. It is
+// passed to the handlers by writer, which preserves the http.Flusher and
+// http.Hijacker of the original http.ResponseWriter.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: w}
+}
+
+// WriteHeader implements interface http.ResponseWriter.
+func (rw *responseWriter) WriteHeader(status int) {
+	if !rw.wroteHeader && status >= http.StatusOK {
+		rw.status = status
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements interface http.ResponseWriter.
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	n, err := rw.ResponseWriter.Write(p)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements interface http.Flusher.
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		if !rw.wroteHeader {
+			rw.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+// Hijack implements interface http.Hijacker.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the ResponseWriter does not implement http.Hijacker")
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the original http.ResponseWriter.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// writer returns the responseWriter implementing only the optional interfaces of
+// the original http.ResponseWriter.
+func (rw *responseWriter) writer() http.ResponseWriter {
+	return exposeInterfaces(rw, rw.ResponseWriter)
+}
+
+// Status returns the status of the response, http.StatusOK if it was not set.
+func (rw *responseWriter) Status() int {
+	if rw.status == 0 {
+		return http.StatusOK
+	}
+	return rw.status
+}
+
+// Written reports whether the response header was written.
+func (rw *responseWriter) Written() bool {
+	return rw.wroteHeader
+}
+
+// wrappedWriter is an http.ResponseWriter that wraps another, implementing its
+// optional interfaces.
+type wrappedWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	Unwrap() http.ResponseWriter
+}
+
+// exposeInterfaces returns w implementing only the http.Flusher and http.Hijacker
+// implemented by the original http.ResponseWriter, so that the handlers detect the
+// features of the connection, as HTTP/2 can not be hijacked.
+func exposeInterfaces(w wrappedWriter, original http.ResponseWriter) http.ResponseWriter {
+	type unwrapper interface {
+		http.ResponseWriter
+		Unwrap() http.ResponseWriter
+	}
+
+	_, flusher := original.(http.Flusher)
+	_, hijacker := original.(http.Hijacker)
+	switch {
+	case flusher && hijacker:
+		return w
+	case flusher:
+		return struct {
+			unwrapper
+			http.Flusher
+		}{w, w}
+	case hijacker:
+		return struct {
+			unwrapper
+			http.Hijacker
+		}{w, w}
+	default:
+		return struct{ unwrapper }{w}
+	}
+}
+
+// responseBuffer buffers the response, so that it can be inspected or stored
+// before being sent.
+type responseBuffer struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header), status: http.StatusOK}
+}
+
+// Header implements interface http.ResponseWriter.
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+// Write implements interface http.ResponseWriter.
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
+
+// WriteHeader implements interface http.ResponseWriter.
+func (b *responseBuffer) WriteHeader(status int) {
+	if !b.wroteHeader && status >= http.StatusOK {
+		b.status = status
+		b.wroteHeader = true
+	}
+}
+
+// writeTo sends the buffered response to w.
+func (b *responseBuffer) writeTo(w http.ResponseWriter) {
+	header := w.Header()
+	for k, v := range b.header {
+		header[k] = v
+	}
+	w.WriteHeader(b.status)
+	w.Write(b.body.Bytes())
+}
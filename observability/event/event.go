@@ -11,8 +11,18 @@ Send an event log to a kafka topic:
 
 	e := event.New("localhost", "user", "New User", event.Info, "New user added to the database")
 
+	// or, to correlate the event with the request ID stored in the context:
+	e := event.NewWithContext(r.Context(), "localhost", "user", "New User", event.Info,
+		"New user added to the database")
+
 	if err = event.Send(e); err != nil {
 	// ...
+
+Send waits for the event to be queued. In the path of a request, TrySend does not
+wait, dropping the event when the queue is full, as while Kafka is unavailable:
+
+	if err = event.TrySend(e); errors.Is(err, event.ErrFull) {
+	// ...
 */
 
 package event
@@ -24,9 +34,11 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/tsmweb/go-helper-api/kafka"
+	"github.com/tsmweb/go-helper-api/requestid"
 )
 
 // EventType represents the event type ("info", "debug", "warning", ...).
@@ -65,6 +77,7 @@ type Event struct {
 	Title     string `json:"title"`
 	Type      string `json:"type"`
 	Detail    string `json:"detail"`
+	RequestID string `json:"request_id,omitempty"`
 	Timestamp string `json:"timestamp"`
 }
 
@@ -80,6 +93,14 @@ func New(host string, user string, title string, eventType EventType, detail str
 	}
 }
 
+// NewWithContext creates an Event instance with the request ID stored in ctx.
+func NewWithContext(ctx context.Context, host string, user string, title string, eventType EventType,
+	detail string) *Event {
+	e := New(host, user, title, eventType, detail)
+	e.RequestID, _ = requestid.FromContext(ctx)
+	return e
+}
+
 func (e Event) toJSON() []byte {
 	b, err := json.Marshal(e)
 	if err != nil {
@@ -88,14 +109,19 @@ func (e Event) toJSON() []byte {
 	return b
 }
 
+// queueSize is the number of events waiting to be published.
+const queueSize = 1024
+
 var (
 	chEvent chan *Event
 	wg      sync.WaitGroup
 	running bool
 	mu      sync.RWMutex // guard running
+	dropped uint64
 
 	ErrClosed  = errors.New("closed eventlog")
 	ErrRunning = errors.New("is already running")
+	ErrFull    = errors.New("eventlog queue is full")
 )
 
 // Init creates a new producer for Apache Kafka and initializes the routines for sending events.
@@ -108,7 +134,7 @@ func Init(producer kafka.Producer) error {
 	mu.RUnlock()
 
 	ctx := context.Background()
-	chEvent = make(chan *Event)
+	chEvent = make(chan *Event, queueSize)
 	running = true
 	wg.Add(1)
 
@@ -116,6 +142,11 @@ func Init(producer kafka.Producer) error {
 		defer wg.Done()
 
 		for event := range chEvent {
+			ctx := ctx
+			if event.RequestID != "" {
+				ctx = requestid.NewContext(ctx, event.RequestID)
+			}
+
 			if err := producer.Publish(ctx, []byte(event.Host), event.toJSON()); err != nil {
 				fmt.Fprintf(os.Stderr, "%s\n", err)
 			}
@@ -139,7 +170,8 @@ func Close() {
 	}
 }
 
-// Send sends the event to the Apache Kafka topic.
+// Send sends the event to the Apache Kafka topic. The request ID of the event is
+// sent in the X-Request-ID header.
 func Send(event *Event) error {
 	mu.RLock()
 	defer mu.RUnlock()
@@ -151,3 +183,27 @@ func Send(event *Event) error {
 	chEvent <- event
 	return nil
 }
+
+// TrySend sends the event like Send, without waiting for the queue: when it is
+// full, the event is dropped and ErrFull is returned.
+func TrySend(event *Event) error {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if !running {
+		return ErrClosed
+	}
+
+	select {
+	case chEvent <- event:
+		return nil
+	default:
+		atomic.AddUint64(&dropped, 1)
+		return ErrFull
+	}
+}
+
+// Dropped returns the number of events dropped by TrySend.
+func Dropped() uint64 {
+	return atomic.LoadUint64(&dropped)
+}
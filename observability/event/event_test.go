@@ -1,9 +1,11 @@
 package event
 
 import (
+	"context"
 	"errors"
 	"github.com/stretchr/testify/mock"
 	kafka "github.com/tsmweb/go-helper-api/observability/internal/mock"
+	"github.com/tsmweb/go-helper-api/requestid"
 	"testing"
 )
 
@@ -57,3 +59,47 @@ func TestEvent_Send(t *testing.T) {
 		t.Log(err)
 	}
 }
+
+func TestEvent_TrySend(t *testing.T) {
+	Close()
+
+	unblock := make(chan struct{})
+	producer := new(kafka.Producer)
+	producer.On("Publish", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil).
+		Run(func(args mock.Arguments) { <-unblock }) // as while Kafka is unavailable.
+	producer.On("Close")
+
+	if err := Init(producer); err != nil {
+		t.Fatal(err)
+	}
+
+	event := New("localhost", "Test", "Object Not Found", Warning, "Could not find the requested object.")
+
+	var err error
+	for i := 0; i < queueSize+2 && err == nil; i++ {
+		err = TrySend(event)
+	}
+	if !errors.Is(err, ErrFull) || Dropped() == 0 {
+		t.Errorf("TrySend() = %v, Dropped() = %d, want ErrFull", err, Dropped())
+	}
+
+	close(unblock)
+	Close()
+
+	if err = TrySend(event); !errors.Is(err, ErrClosed) {
+		t.Errorf("TrySend() after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestEvent_NewWithContext(t *testing.T) {
+	ctx := requestid.NewContext(context.Background(), "req-123")
+
+	event := NewWithContext(ctx, "localhost", "Test", "Object Not Found", Warning,
+		"Could not find the requested object.")
+
+	if event.RequestID != "req-123" {
+		t.Errorf("RequestID = %q, want %q", event.RequestID, "req-123")
+	}
+	t.Log(string(event.toJSON()))
+}
@@ -0,0 +1,40 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/tsmweb/go-helper-api/cerror"
+)
+
+// reporter sends the reports of the errors as Error events.
+type reporter struct {
+	host string
+	send func(event *Event) error
+}
+
+// NewReporter creates a cerror.Reporter that sends the reports of the errors as
+// Error events of the host, with the report as JSON in the detail and the request
+// ID stored in the context. Events are sent by TrySend, dropped when the queue is
+// full, so reporting does not block the request; Init must be called first.
+// Wrap it with cerror.Deduplicate to not flood the topic with an error storm:
+//
+//	reporter := cerror.Deduplicate(event.NewReporter("localhost"), time.Minute)
+//	httputil.ErrorReporter = reporter
+func NewReporter(host string) cerror.Reporter {
+	return &reporter{
+		host: host,
+		send: TrySend,
+	}
+}
+
+// Report implements interface cerror.Reporter.
+func (r *reporter) Report(ctx context.Context, report *cerror.Report) error {
+	detail, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	e := NewWithContext(ctx, r.host, "", string(report.Code), Error, string(detail))
+	return r.send(e)
+}
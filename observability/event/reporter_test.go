@@ -0,0 +1,39 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/tsmweb/go-helper-api/cerror"
+	"github.com/tsmweb/go-helper-api/requestid"
+)
+
+func TestReporter_Report(t *testing.T) {
+	var sent *Event
+	r := NewReporter("localhost").(*reporter)
+	r.send = func(event *Event) error {
+		sent = event
+		return nil
+	}
+
+	ctx := requestid.NewContext(context.Background(), "REQUEST-ID")
+	report := cerror.NewReport(cerror.Internal(errors.New("connection lost")), nil)
+	if err := r.Report(ctx, report); err != nil {
+		t.Fatal(err)
+	}
+
+	if sent.Type != Error.String() || sent.Host != "localhost" || sent.Title != "internal" ||
+		sent.RequestID != "REQUEST-ID" {
+		t.Errorf("Event = %+v", sent)
+	}
+
+	var detail cerror.Report
+	if err := json.Unmarshal([]byte(sent.Detail), &detail); err != nil {
+		t.Fatal(err)
+	}
+	if detail.Fingerprint != report.Fingerprint || detail.Message != report.Message {
+		t.Errorf("Detail = %+v", detail)
+	}
+}
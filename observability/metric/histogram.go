@@ -0,0 +1,67 @@
+package metric
+
+import (
+	"sort"
+	"sync"
+)
+
+// DefaultLatencyBuckets are the upper bounds in milliseconds of the buckets of a
+// latency histogram.
+var DefaultLatencyBuckets = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// HistogramSnapshot is the state of a Histogram. Counts has one count per bucket,
+// plus the count of the observations greater than the last bucket.
+type HistogramSnapshot struct {
+	Buckets []float64 `json:"buckets"`
+	Counts  []uint64  `json:"counts"`
+	Count   uint64    `json:"count"`
+	Sum     float64   `json:"sum"`
+}
+
+// Histogram counts the observations in buckets. It is safe for concurrent use.
+type Histogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+	mu      sync.Mutex // guard counts, count and sum
+}
+
+// NewHistogram creates a Histogram with the upper bounds of the buckets, by
+// default DefaultLatencyBuckets.
+func NewHistogram(buckets []float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)+1),
+	}
+}
+
+// Observe adds the value to the histogram.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+
+	h.mu.Lock()
+	h.counts[i]++
+	h.count++
+	h.sum += v
+	h.mu.Unlock()
+}
+
+// Snapshot returns the state of the histogram.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return HistogramSnapshot{
+		Buckets: h.buckets,
+		Counts:  append([]uint64(nil), h.counts...),
+		Count:   h.count,
+		Sum:     h.sum,
+	}
+}
@@ -3,6 +3,13 @@ Package metric implements routines to collect metrics from localhost and send to
 in Apache Kafka. The metrics collected are: "uptime", "os", "total memory", "memory used",
 "cpu count", "cpu user", "cpu system", "cpu idle" and "num goroutines".
 
+The metrics of the application, as the HTTP metrics of the middleware package, are
+published with the metrics of localhost by registering a Collector:
+
+	err := metric.Register(collector)
+	if err != nil {
+		// ...
+
 Starts collecting and sending metrics:
 
 	producer := kafka.New([]string{"localhost:9094"}, "CLIENT_ID").NewProducer("TOPIC_NAME")
@@ -43,6 +50,8 @@ type metric struct {
 	CPUIdle     float64 `json:"cpu_idle"`
 	Goroutines  int     `json:"goroutines"`
 	Timestamp   string  `json:"timestamp"`
+
+	Metrics map[string]interface{} `json:"metrics,omitempty"`
 }
 
 // newMetric creates a metric instance.
@@ -89,6 +98,7 @@ func newMetric(host string) (*metric, error) {
 		CPUIdle:     cpuIdle,
 		Goroutines:  runtime.NumGoroutine(),
 		Timestamp:   time.Now().Format("2006-01-02T15:04:05-0700"), // yyyy-MM-dd'T'HH:mm:ssZ
+		Metrics:     Collect(),
 	}
 
 	return m, nil
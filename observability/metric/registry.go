@@ -0,0 +1,67 @@
+package metric
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrCollectorRegistered indicates that a collector with the same name is already registered.
+var ErrCollectorRegistered = errors.New("collector is already registered")
+
+// Collector collects metrics of the application, published with the metrics of
+// localhost on each tick.
+type Collector interface {
+	// Name returns the key of the metrics in the published data.
+	Name() string
+
+	// Collect returns the current metrics, which must be serializable to JSON.
+	Collect() interface{}
+}
+
+var (
+	collectors   = make(map[string]Collector)
+	collectorsMu sync.RWMutex // guard collectors
+)
+
+// Register registers the collector, whose metrics are published on each tick.
+func Register(c Collector) error {
+	collectorsMu.Lock()
+	defer collectorsMu.Unlock()
+
+	if _, ok := collectors[c.Name()]; ok {
+		return ErrCollectorRegistered
+	}
+	collectors[c.Name()] = c
+	return nil
+}
+
+// Unregister removes the collector registered with the name.
+func Unregister(name string) {
+	collectorsMu.Lock()
+	defer collectorsMu.Unlock()
+
+	delete(collectors, name)
+}
+
+// Collect returns the metrics of the registered collectors by name.
+func Collect() map[string]interface{} {
+	collectorsMu.RLock()
+	defer collectorsMu.RUnlock()
+
+	if len(collectors) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(collectors))
+	for name := range collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metrics := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		metrics[name] = collectors[name].Collect()
+	}
+	return metrics
+}
@@ -0,0 +1,62 @@
+package metric
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type collector struct {
+	name  string
+	value interface{}
+}
+
+func (c collector) Name() string         { return c.name }
+func (c collector) Collect() interface{} { return c.value }
+
+func TestRegister(t *testing.T) {
+	defer Unregister("test")
+
+	if err := Register(collector{"test", 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(collector{"test", 2}); !errors.Is(err, ErrCollectorRegistered) {
+		t.Errorf("Register() error = %v, want %v", err, ErrCollectorRegistered)
+	}
+
+	metrics := Collect()
+	if metrics["test"] != 1 {
+		t.Errorf("Collect() = %v", metrics)
+	}
+
+	m, err := newMetric("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Metrics["test"] != 1 {
+		t.Errorf("metric.Metrics = %v", m.Metrics)
+	}
+
+	Unregister("test")
+	if metrics := Collect(); metrics != nil {
+		t.Errorf("Collect() after Unregister() = %v", metrics)
+	}
+}
+
+func TestHistogram_Observe(t *testing.T) {
+	h := NewHistogram([]float64{10, 1, 5})
+	for _, v := range []float64{0.5, 1, 3, 7, 10, 20} {
+		h.Observe(v)
+	}
+
+	s := h.Snapshot()
+	if !reflect.DeepEqual(s.Buckets, []float64{1, 5, 10}) {
+		t.Errorf("Buckets = %v", s.Buckets)
+	}
+	if !reflect.DeepEqual(s.Counts, []uint64{2, 1, 2, 1}) {
+		t.Errorf("Counts = %v, want [2 1 2 1]", s.Counts)
+	}
+	if s.Count != 6 || s.Sum != 41.5 {
+		t.Errorf("Count = %d, Sum = %v, want 6 and 41.5", s.Count, s.Sum)
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Package requestid provides the request ID that correlates an HTTP request with
+the Kafka events and the event logs it produces.
+
+Store and retrieve the request ID:
+
+	ctx := requestid.NewContext(r.Context(), requestid.New())
+	// ...
+
+	id, ok := requestid.FromContext(ctx)
+	if ok {
+	// ...
+*/
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Header is the name of the HTTP and Kafka header that carries the request ID.
+const Header = "X-Request-ID"
+
+type contextKey struct{}
+
+// New generates a new request ID.
+func New() string {
+	return uuid.New().String()
+}
+
+// NewContext returns a copy of ctx that carries the request ID.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok && id != ""
+}
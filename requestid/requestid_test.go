@@ -0,0 +1,20 @@
+package requestid
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRequestID_Context(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("FromContext() found a request ID in an empty context")
+	}
+
+	id := New()
+	ctx := NewContext(context.Background(), id)
+
+	got, ok := FromContext(ctx)
+	if !ok || got != id {
+		t.Errorf("FromContext() = %q, %v, want %q, true", got, ok, id)
+	}
+}
@@ -7,7 +7,10 @@ package hashutil
 import (
 	"crypto/sha1"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 // HashSHA1 generates and returns an SHA1 hash.
@@ -53,3 +56,27 @@ func VerifySHA256(hashedValue, value string) (bool, error) {
 
 	return hashedValue == hash, nil
 }
+
+// HashBcrypt generates and returns a bcrypt hash, salted and with the default cost,
+// suitable to store passwords.
+func HashBcrypt(value string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(value), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// VerifyBcrypt checks if the plaintext value matches the given bcrypt hash.
+func VerifyBcrypt(hashedValue, value string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hashedValue), []byte(value))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
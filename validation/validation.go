@@ -0,0 +1,375 @@
+/*
+Package validation validates the fields of structs through the "validate" tag,
+collecting all the failures in a cerror.ErrValidateModel with the errors of each
+field, which httputil.RespondWithProblem sends to the client.
+
+The rules of a field are separated by commas:
+
+	type User struct {
+		Name    string   `json:"name" validate:"required,min=3,max=50"`
+		Email   string   `json:"email" validate:"required,email"`
+		Role    string   `json:"role" validate:"oneof=admin user"`
+		Code    string   `json:"code" validate:"regex=^[A-Z]{3}[0-9]+$"`
+		Address *Address `json:"address" validate:"required"`
+		Phones  []string `json:"phones" validate:"max=3,dive,min=8"`
+	}
+
+	if err := validation.Struct(user); err != nil {
+		httputil.RespondWithProblem(w, r, err)
+		return
+	}
+
+The built-in rules are:
+
+	required  the value is not the zero value.
+	min=N     the number is at least N, or the string, slice or map has at least N elements.
+	max=N     the number is at most N, or the string, slice or map has at most N elements.
+	email     the string is an e-mail address.
+	oneof=A B the value is one of the values separated by spaces.
+	regex=RE  the string matches the regular expression, which can not contain commas.
+	dive      the following rules apply to the elements of the slice or map.
+
+Fields without the required rule are optional: the other rules are skipped when the
+value is the zero value. Structs, pointers to structs and their slices are
+validated recursively. The fields are named by their json tag, as in
+"address.city" and "items[1].name".
+
+Custom rules are registered with RegisterRule:
+
+	validation.RegisterRule("cpf", func(v reflect.Value, _ string) bool {
+		return isCPF(v.String())
+	}, "{field} is not a valid CPF")
+*/
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tsmweb/go-helper-api/cerror"
+)
+
+// RuleFunc reports whether the value of a field satisfies the rule with the
+// parameter, as "3" in "min=3".
+type RuleFunc func(v reflect.Value, param string) bool
+
+// Validator validates the fields of structs.
+type Validator interface {
+	// Struct validates the struct, or pointer to struct, returning a
+	// *cerror.ErrValidateModel with the errors of the fields, or nil if valid.
+	Struct(v interface{}) error
+
+	// RegisterRule registers the rule with the name, replacing a rule with the
+	// same name. The message of the errors may contain {field} and {param}.
+	RegisterRule(name string, fn RuleFunc, message string)
+}
+
+type rule struct {
+	fn      RuleFunc
+	message string
+}
+
+type validator struct {
+	rules   map[string]rule
+	regexps map[string]*regexp.Regexp
+	mu      sync.RWMutex // guard rules and regexps
+}
+
+// New creates a Validator with the built-in rules.
+func New() Validator {
+	v := &validator{
+		rules:   make(map[string]rule),
+		regexps: make(map[string]*regexp.Regexp),
+	}
+
+	v.RegisterRule("required", func(rv reflect.Value, _ string) bool {
+		return !rv.IsZero()
+	}, "{field} is required")
+	v.RegisterRule("min", func(rv reflect.Value, param string) bool {
+		n, ok := measure(rv)
+		return ok && n >= parseFloat("min", param)
+	}, "{field} must be at least {param}")
+	v.RegisterRule("max", func(rv reflect.Value, param string) bool {
+		n, ok := measure(rv)
+		return ok && n <= parseFloat("max", param)
+	}, "{field} must be at most {param}")
+	v.RegisterRule("email", func(rv reflect.Value, _ string) bool {
+		addr, err := mail.ParseAddress(rv.String())
+		return err == nil && addr.Address == rv.String()
+	}, "{field} must be a valid e-mail address")
+	v.RegisterRule("oneof", func(rv reflect.Value, param string) bool {
+		value := fmt.Sprint(rv.Interface())
+		for _, option := range strings.Fields(param) {
+			if value == option {
+				return true
+			}
+		}
+		return false
+	}, "{field} must be one of: {param}")
+	v.RegisterRule("regex", func(rv reflect.Value, param string) bool {
+		return rv.Kind() == reflect.String && v.regexp(param).MatchString(rv.String())
+	}, "{field} has an invalid format")
+
+	return v
+}
+
+// RegisterRule implements interface Validator.
+func (v *validator) RegisterRule(name string, fn RuleFunc, message string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.rules[name] = rule{fn: fn, message: message}
+}
+
+// Struct implements interface Validator.
+func (v *validator) Struct(s interface{}) error {
+	rv := reflect.ValueOf(s)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return &cerror.ErrValidateModel{Msg: "model is nil"}
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: %T is not a struct", s))
+	}
+
+	var errs []cerror.FieldError
+	v.validateStruct(rv, "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return &cerror.ErrValidateModel{Fields: errs}
+}
+
+func (v *validator) validateStruct(rv reflect.Value, prefix string, errs *[]cerror.FieldError) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name := fieldName(sf)
+		if name == "" {
+			continue
+		}
+		path := prefix + name
+		if sf.Anonymous && sf.Tag.Get("json") == "" { // the fields of embedded structs are promoted.
+			path = strings.TrimSuffix(prefix, ".")
+		}
+
+		v.validateValue(rv.Field(i), path, sf.Tag.Get("validate"), errs)
+	}
+}
+
+// validateValue applies the rules of the tag to the value, then validates the
+// structs it contains.
+func (v *validator) validateValue(rv reflect.Value, path, tag string, errs *[]cerror.FieldError) {
+	rules := splitRules(tag)
+
+	if len(rules) > 0 && !hasRule(rules, "required") && rv.IsZero() {
+		return // optional field.
+	}
+
+	for i, r := range rules {
+		if r.name == "dive" {
+			v.validateElements(rv, path, rules[i+1:], errs)
+			return
+		}
+
+		if r.name != "required" {
+			if rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+				if rv.IsNil() {
+					continue
+				}
+				rv = rv.Elem()
+			}
+		}
+
+		if !v.rule(r.name).fn(rv, r.param) {
+			*errs = append(*errs, v.fieldError(path, r))
+			return
+		}
+	}
+
+	v.validateNested(rv, path, errs)
+}
+
+// validateElements applies the rules to the elements of the slice or map.
+func (v *validator) validateElements(rv reflect.Value, path string, rules []tagRule, errs *[]cerror.FieldError) {
+	tag := joinRules(rules)
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			v.validateValue(rv.Index(i), fmt.Sprintf("%s[%d]", path, i), tag, errs)
+		}
+	case reflect.Map:
+		iter := rv.MapRange()
+		for iter.Next() {
+			v.validateValue(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), tag, errs)
+		}
+	default:
+		panic(fmt.Sprintf("validation: dive on %s of type %s", path, rv.Type()))
+	}
+}
+
+// validateNested validates the structs, pointers to structs and their slices.
+func (v *validator) validateNested(rv reflect.Value, path string, errs *[]cerror.FieldError) {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		prefix := path
+		if prefix != "" {
+			prefix += "."
+		}
+		v.validateStruct(rv, prefix, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if isStruct(rv.Index(i).Type()) {
+				v.validateNested(rv.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	}
+}
+
+func (v *validator) rule(name string) rule {
+	v.mu.RLock()
+	r, ok := v.rules[name]
+	v.mu.RUnlock()
+
+	if !ok {
+		panic(fmt.Sprintf("validation: unknown rule %q", name))
+	}
+	return r
+}
+
+func (v *validator) regexp(pattern string) *regexp.Regexp {
+	v.mu.RLock()
+	re, ok := v.regexps[pattern]
+	v.mu.RUnlock()
+	if ok {
+		return re
+	}
+
+	re = regexp.MustCompile(pattern)
+	v.mu.Lock()
+	v.regexps[pattern] = re
+	v.mu.Unlock()
+	return re
+}
+
+func (v *validator) fieldError(path string, r tagRule) cerror.FieldError {
+	message := strings.NewReplacer("{field}", path, "{param}", r.param).Replace(v.rule(r.name).message)
+	return cerror.FieldError{Field: path, Rule: r.name, Param: r.param, Message: message}
+}
+
+type tagRule struct {
+	name  string
+	param string
+}
+
+func splitRules(tag string) []tagRule {
+	if tag == "" || tag == "-" {
+		return nil
+	}
+
+	var rules []tagRule
+	for _, s := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(s), "=")
+		if name != "" {
+			rules = append(rules, tagRule{name: name, param: param})
+		}
+	}
+	return rules
+}
+
+func hasRule(rules []tagRule, name string) bool {
+	for _, r := range rules {
+		if r.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func joinRules(rules []tagRule) string {
+	s := make([]string, len(rules))
+	for i, r := range rules {
+		s[i] = r.name
+		if r.param != "" {
+			s[i] += "=" + r.param
+		}
+	}
+	return strings.Join(s, ",")
+}
+
+// fieldName returns the name of the field in its json tag, or its Go name.
+func fieldName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return sf.Name
+	default:
+		return name
+	}
+}
+
+// measure returns the number, or the length of the string, slice or map.
+func measure(rv reflect.Value) (float64, bool) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.String:
+		return float64(len([]rune(rv.String()))), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(rv.Len()), true
+	default:
+		return 0, false
+	}
+}
+
+func parseFloat(rule, param string) float64 {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validation: invalid parameter of rule %s: %q", rule, param))
+	}
+	return n
+}
+
+func isStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+var defaultValidator = New()
+
+// Struct validates the struct with the default Validator.
+func Struct(v interface{}) error {
+	return defaultValidator.Struct(v)
+}
+
+// RegisterRule registers the rule in the default Validator.
+func RegisterRule(name string, fn RuleFunc, message string) {
+	defaultValidator.RegisterRule(name, fn, message)
+}
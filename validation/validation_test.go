@@ -0,0 +1,129 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tsmweb/go-helper-api/cerror"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `json:"zip" validate:"regex=^[0-9]{5}-[0-9]{3}$"`
+}
+
+type item struct {
+	Name     string `json:"name" validate:"required"`
+	Quantity int    `json:"quantity" validate:"min=1,max=10"`
+}
+
+type Audit struct {
+	CreatedBy string `json:"created_by" validate:"required"`
+}
+
+type user struct {
+	Audit
+	Name     string            `json:"name" validate:"required,min=3,max=10"`
+	Email    string            `json:"email" validate:"required,email"`
+	Role     string            `json:"role" validate:"oneof=admin user"`
+	Age      *int              `json:"age" validate:"min=18"`
+	Address  *address          `json:"address" validate:"required"`
+	Items    []item            `json:"items" validate:"max=2"`
+	Tags     []string          `json:"tags" validate:"dive,min=2"`
+	Labels   map[string]string `json:"labels" validate:"dive,oneof=a b"`
+	Nickname string            `json:"-" validate:"required"`
+	internal string            `validate:"required"`
+}
+
+func TestStruct(t *testing.T) {
+	age := 17
+	u := &user{
+		Name:    "Jo",
+		Email:   "jo@",
+		Role:    "root",
+		Age:     &age,
+		Address: &address{Zip: "123"},
+		Items:   []item{{Name: "a", Quantity: 1}, {Quantity: 11}},
+		Tags:    []string{"go", "x"},
+		Labels:  map[string]string{"k": "c"},
+	}
+
+	err := Struct(u)
+
+	var verr *cerror.ErrValidateModel
+	if !errors.As(err, &verr) {
+		t.Fatalf("Struct() error = %v, want *cerror.ErrValidateModel", err)
+	}
+	if !errors.Is(err, cerror.ErrValidation) {
+		t.Error("errors.Is(err, cerror.ErrValidation) = false")
+	}
+
+	got := make(map[string]string)
+	for _, f := range verr.Fields {
+		got[f.Field] = f.Rule
+	}
+	want := map[string]string{
+		"created_by":        "required",
+		"name":              "min",
+		"email":             "email",
+		"role":              "oneof",
+		"age":               "min",
+		"address.city":      "required",
+		"address.zip":       "regex",
+		"items[1].name":     "required",
+		"items[1].quantity": "max",
+		"tags[1]":           "min",
+		"labels[k]":         "oneof",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields = %v, want %v", got, want)
+	}
+
+	for _, f := range verr.Fields {
+		if f.Field == "name" && f.Message != "name must be at least 3" {
+			t.Errorf("message = %q", f.Message)
+		}
+	}
+}
+
+func TestStruct_Valid(t *testing.T) {
+	age := 30
+	u := user{
+		Audit:   Audit{CreatedBy: "admin"},
+		Name:    "Maria",
+		Email:   "maria@example.com",
+		Age:     &age,
+		Address: &address{City: "Natal", Zip: "59000-000"},
+	}
+
+	if err := Struct(u); err != nil {
+		t.Errorf("Struct() error = %v", err)
+	}
+}
+
+func TestRegisterRule(t *testing.T) {
+	v := New()
+	v.RegisterRule("upper", func(rv reflect.Value, _ string) bool {
+		return rv.String() == strings.ToUpper(rv.String())
+	}, "{field} must be upper case")
+
+	type code struct {
+		Value string `validate:"required,upper"`
+	}
+
+	err := v.Struct(code{Value: "abc"})
+
+	var verr *cerror.ErrValidateModel
+	if !errors.As(err, &verr) || len(verr.Fields) != 1 {
+		t.Fatalf("Struct() error = %v", err)
+	}
+	if verr.Fields[0].Message != "Value must be upper case" || verr.Error() != "Value: Value must be upper case" {
+		t.Errorf("Fields = %+v, Error() = %q", verr.Fields, verr.Error())
+	}
+
+	if err := v.Struct(code{Value: "ABC"}); err != nil {
+		t.Errorf("Struct() error = %v", err)
+	}
+}